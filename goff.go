@@ -1,6 +1,7 @@
 package goff
 
 import (
+	"database/sql"
 	"time"
 
 	pkggoff "github.com/0mjs/goff/pkg/goff"
@@ -13,16 +14,47 @@ type (
 	Hooks   = pkggoff.Hooks
 	Reason  = pkggoff.Reason
 	Option  = pkggoff.Option
+
+	AllFlagsOptions = pkggoff.AllFlagsOptions
+	FlagState       = pkggoff.FlagState
+
+	StickyStore       = pkggoff.StickyStore
+	MemoryStickyStore = pkggoff.MemoryStickyStore
+	SQLiteStickyStore = pkggoff.SQLiteStickyStore
 )
 
+// Flag is a typed handle to a single flag; see BoolFlag.
+type Flag[T FlagValue] = pkggoff.Flag[T]
+
+// FlagValue is the set of value types supported by typed flag handles.
+type FlagValue = pkggoff.FlagValue
+
+// EvaluationDetail describes the outcome of a single flag evaluation.
+type EvaluationDetail[T any] = pkggoff.EvaluationDetail[T]
+
 // Re-export constants
 const (
-	Match    = pkggoff.Match
-	Percent  = pkggoff.Percent
-	Default  = pkggoff.Default
-	Disabled = pkggoff.Disabled
-	Missing  = pkggoff.Missing
-	Error    = pkggoff.Error
+	Match              = pkggoff.Match
+	Percent            = pkggoff.Percent
+	Default            = pkggoff.Default
+	Disabled           = pkggoff.Disabled
+	Missing            = pkggoff.Missing
+	Error              = pkggoff.Error
+	TargetMatch        = pkggoff.TargetMatch
+	TypeMismatch       = pkggoff.TypeMismatch
+	MissingBucketKey   = pkggoff.MissingBucketKey
+	Inactive           = pkggoff.Inactive
+	PrerequisiteFailed = pkggoff.PrerequisiteFailed
+
+	BucketKeyFallthrough = pkggoff.BucketKeyFallthrough
+)
+
+// Re-export errors
+var (
+	ErrFlagNotFound = pkggoff.ErrFlagNotFound
+	ErrTypeMismatch = pkggoff.ErrTypeMismatch
+	ErrNoValue      = pkggoff.ErrNoValue
+	ErrEvaluation   = pkggoff.ErrEvaluation
 )
 
 // New creates a new Client with the given options.
@@ -44,3 +76,56 @@ func WithAutoReload(interval time.Duration) Option {
 func WithHooks(hooks Hooks) Option {
 	return pkggoff.WithHooks(hooks)
 }
+
+// WithStickyStore keeps contexts on their variant for flags marked sticky.
+func WithStickyStore(store StickyStore) Option {
+	return pkggoff.WithStickyStore(store)
+}
+
+// WithClock sets the clock used for time-based targeting. It defaults to
+// time.Now.
+func WithClock(now func() time.Time) Option {
+	return pkggoff.WithClock(now)
+}
+
+// NewMemoryStickyStore returns an in-process LRU sticky store.
+func NewMemoryStickyStore(capacity int) *MemoryStickyStore {
+	return pkggoff.NewMemoryStickyStore(capacity)
+}
+
+// NewSQLiteStickyStore returns a sticky store backed by a SQLite database.
+func NewSQLiteStickyStore(db *sql.DB) (*SQLiteStickyStore, error) {
+	return pkggoff.NewSQLiteStickyStore(db)
+}
+
+// Object evaluates a json flag and decodes the selected value into a new T.
+// It returns def if the flag cannot be evaluated or decoded.
+func Object[T any](c Client, key string, ctx Context, def T) T {
+	return pkggoff.Object(c, key, ctx, def)
+}
+
+// NewFlag returns a typed handle to the flag key.
+func NewFlag[T FlagValue](c Client, key string) *Flag[T] {
+	return pkggoff.NewFlag[T](c, key)
+}
+
+// BoolFlag returns a handle to a boolean flag that resolves the flag once
+// per configuration snapshot.
+func BoolFlag(c Client, key string) *Flag[bool] {
+	return pkggoff.BoolFlag(c, key)
+}
+
+// StringFlag returns a handle to a string flag.
+func StringFlag(c Client, key string) *Flag[string] {
+	return pkggoff.StringFlag(c, key)
+}
+
+// IntFlag returns a handle to an integer flag.
+func IntFlag(c Client, key string) *Flag[int64] {
+	return pkggoff.IntFlag(c, key)
+}
+
+// FloatFlag returns a handle to a floating-point flag.
+func FloatFlag(c Client, key string) *Flag[float64] {
+	return pkggoff.FloatFlag(c, key)
+}
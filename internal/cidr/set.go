@@ -0,0 +1,134 @@
+// Package cidr matches IP addresses against sets of network prefixes.
+package cidr
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Set is a set of IPv4 and IPv6 prefixes stored in a binary trie, so a
+// lookup costs at most one step per address bit however many prefixes the
+// set holds.
+type Set struct {
+	v4, v6 *node
+	len    int
+}
+
+type node struct {
+	children [2]*node
+	terminal bool // a prefix ends here; every address below it matches
+}
+
+// ParseSet parses CIDRs such as "10.0.0.0/8" or "2001:db8::/32". A bare
+// address is a single-host prefix.
+func ParseSet(cidrs []string) (*Set, error) {
+	s := &Set{v4: &node{}, v6: &node{}}
+	for _, c := range cidrs {
+		prefix, err := parsePrefix(c)
+		if err != nil {
+			return nil, err
+		}
+		s.Insert(prefix)
+	}
+	return s, nil
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", s, err)
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", s, err)
+	}
+	return prefix, nil
+}
+
+// Insert adds a prefix to the set. IPv4-mapped IPv6 prefixes such as
+// ::ffff:10.0.0.0/104 are stored as IPv4, since Contains matches mapped
+// addresses as IPv4.
+func (s *Set) Insert(prefix netip.Prefix) {
+	prefix = prefix.Masked()
+	addr := prefix.Addr()
+	if addr.Is4In6() && prefix.Bits() >= 96 {
+		addr = addr.Unmap()
+		prefix = netip.PrefixFrom(addr, prefix.Bits()-96)
+	}
+	n := s.root(addr)
+	for i := 0; i < prefix.Bits(); i++ {
+		if n.terminal {
+			return // already covered by a shorter prefix
+		}
+		b := bit(addr, i)
+		if n.children[b] == nil {
+			n.children[b] = &node{}
+		}
+		n = n.children[b]
+	}
+	if !n.terminal {
+		s.len += 1 - n.count()
+		n.terminal = true
+		n.children = [2]*node{} // longer prefixes are now redundant
+	}
+}
+
+// count returns the number of prefixes ending at or below n.
+func (n *node) count() int {
+	if n == nil {
+		return 0
+	}
+	if n.terminal {
+		return 1
+	}
+	return n.children[0].count() + n.children[1].count()
+}
+
+// Contains reports whether addr falls within any prefix of the set.
+// IPv4-mapped IPv6 addresses are matched as IPv4.
+func (s *Set) Contains(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	n := s.root(addr)
+	for i := 0; n != nil; i++ {
+		if n.terminal {
+			return true
+		}
+		if i == addr.BitLen() {
+			return false
+		}
+		n = n.children[bit(addr, i)]
+	}
+	return false
+}
+
+// Len returns the number of prefixes in the set, not counting prefixes
+// contained in others.
+func (s *Set) Len() int {
+	return s.len
+}
+
+func (s *Set) root(addr netip.Addr) *node {
+	if addr.Is4() {
+		return s.v4
+	}
+	return s.v6
+}
+
+// bit returns bit i of addr, counting from the most significant.
+func bit(addr netip.Addr, i int) int {
+	if addr.Is4() {
+		b := addr.As4()
+		return int(b[i/8]>>(7-i%8)) & 1
+	}
+	b := addr.As16()
+	return int(b[i/8]>>(7-i%8)) & 1
+}
@@ -0,0 +1,113 @@
+package cidr
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+)
+
+func TestSet_Contains(t *testing.T) {
+	set, err := ParseSet([]string{"10.0.0.0/8", "192.168.1.0/24", "203.0.113.7", "2001:db8::/32", "::ffff:198.51.100.0/120"})
+	if err != nil {
+		t.Fatalf("ParseSet() error = %v", err)
+	}
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"11.0.0.1", false},
+		{"192.168.1.255", true},
+		{"192.168.2.1", false},
+		{"203.0.113.7", true},
+		{"203.0.113.8", false},
+		{"::ffff:10.0.0.1", true},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"198.51.100.20", true},
+		{"::ffff:198.51.100.20", true},
+		{"198.51.101.20", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := set.Contains(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+
+	if set.Contains(netip.Addr{}) {
+		t.Error("Contains() should be false for the zero address")
+	}
+}
+
+func TestSet_OverlappingPrefixes(t *testing.T) {
+	set, err := ParseSet([]string{"10.1.0.0/16", "10.0.0.0/8", "10.2.3.0/24", "10.0.0.5/8"})
+	if err != nil {
+		t.Fatalf("ParseSet() error = %v", err)
+	}
+	if set.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", set.Len())
+	}
+	if !set.Contains(netip.MustParseAddr("10.200.0.1")) {
+		t.Error("Contains() should match the covering prefix")
+	}
+}
+
+func TestSet_MappedPrefix(t *testing.T) {
+	set, err := ParseSet([]string{"::ffff:10.0.0.0/104", "10.1.0.0/16"})
+	if err != nil {
+		t.Fatalf("ParseSet() error = %v", err)
+	}
+	if set.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", set.Len())
+	}
+	for _, addr := range []string{"10.2.3.4", "::ffff:10.2.3.4"} {
+		if !set.Contains(netip.MustParseAddr(addr)) {
+			t.Errorf("Contains(%s) should match ::ffff:10.0.0.0/104", addr)
+		}
+	}
+}
+
+func TestSet_AllAddresses(t *testing.T) {
+	set, err := ParseSet([]string{"0.0.0.0/0"})
+	if err != nil {
+		t.Fatalf("ParseSet() error = %v", err)
+	}
+	if !set.Contains(netip.MustParseAddr("8.8.8.8")) {
+		t.Error("0.0.0.0/0 should contain every IPv4 address")
+	}
+	if set.Contains(netip.MustParseAddr("2001:db8::1")) {
+		t.Error("0.0.0.0/0 should not contain IPv6 addresses")
+	}
+}
+
+func TestParseSet_Invalid(t *testing.T) {
+	for _, c := range []string{"10.0.0.0/33", "10.0.0/8", "not-an-ip", "2001:db8::/129"} {
+		if _, err := ParseSet([]string{c}); err == nil {
+			t.Errorf("ParseSet(%q) should fail", c)
+		}
+	}
+}
+
+func BenchmarkSet_Contains(b *testing.B) {
+	cidrs := make([]string, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		cidrs = append(cidrs, fmt.Sprintf("%d.%d.%d.0/24", 10+i/65536, i/256%256, i%256))
+	}
+	set, err := ParseSet(cidrs)
+	if err != nil {
+		b.Fatal(err)
+	}
+	addr := netip.MustParseAddr("10.39.15.200")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !set.Contains(addr) {
+			b.Fatal("expected match")
+		}
+	}
+}
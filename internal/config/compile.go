@@ -1,37 +1,73 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/0mjs/goff/internal/expr"
 )
 
 // Compiled represents a compiled, immutable configuration ready for evaluation.
 type Compiled struct {
-	Flags map[string]*CompiledFlag
+	Flags    map[string]*CompiledFlag
+	Segments map[string]*CompiledSegment
+	Version  uint64 // snapshot version, assigned by the loader
 }
 
 // CompiledFlag represents a compiled flag ready for evaluation.
 type CompiledFlag struct {
-	Enabled  bool
-	Type     string         // "bool" | "string"
-	Variants map[string]int // variant -> percentage (0-100)
-	Rules    []*CompiledRule
-	Default  any // bool for bool flags, string for string flags
+	Enabled       bool
+	Type          string                     // "bool" | "string" | "int" | "float" | "json"
+	Variants      map[string]int             // variant -> percentage (0-100)
+	Distribution  *Distribution              // precomputed bucket table for Variants; nil if there are none
+	Values        map[string]any             // variant -> typed value (bool, string, int64, float64 or decoded JSON)
+	Payloads      map[string]json.RawMessage // json only: variant -> encoded payload
+	Rules         []*CompiledRule
+	Default       any // normalized value of the flag type; for json flags, the default variant name
+	Tags          []string
+	ClientSide    bool
+	Salt          uint64 // hashed Flag.Salt; 0 if unset
+	BucketBy      string // attribute to bucket by; empty for the context key
+	Sticky        bool   // consult the sticky store before bucketing
+	Rollout       *CompiledRollout
+	Schedule      *CompiledSchedule // nil if the flag is always active
+	Prerequisites []CompiledPrerequisite
+	Targets       map[string]string // context key -> variant; nil if there are no targets
+
+	lazy lazyDistribution // Distribution for flags built without Compile
 }
 
 // CompiledRule represents a compiled rule ready for evaluation.
 type CompiledRule struct {
-	Conditions []*CompiledCondition
-	Variants   map[string]int // variant -> percentage (0-100)
+	ID           string               // Rule.ID; empty if the rule has none
+	When         *CompiledNode        // condition tree; nil if the rule has no conditions
+	Conditions   []*CompiledCondition // flat v1 rules only: the leaves of When, in order
+	Expr         *expr.Program        // compiled Rule.Expr; nil if the rule has none
+	Variants     map[string]int       // variant -> percentage (0-100)
+	Distribution *Distribution        // precomputed bucket table for Variants
+	Salt         uint64               // hashed salt, inherited from the flag unless the rule sets one
+	BucketBy     string               // attribute to bucket by, inherited from the flag unless the rule sets one
+	Rollout      *CompiledRollout
+	Schedule     *CompiledSchedule // nil if the rule always applies
+	Segment      *CompiledSegment  // nil if the rule does not refer to a segment
+
+	lazy lazyDistribution // Distribution for rules built without Compile
 }
 
 // CompiledCondition represents a compiled condition.
 type CompiledCondition struct {
 	Attr  string
+	Path  []PathSegment // Attr split into segments if it is a path; nil otherwise
 	Op    string
-	Value any
+	Code  OpCode         // Op resolved by Compile; zero for conditions built by hand
+	Value any            // compiled by CompileOperand when Code is set
 	Regex *regexp.Regexp // compiled regex for "matches" operator
 	IsAll bool           // true if part of "all", false if part of "any"
+
+	lazy lazyOperand // Code and Value for conditions built without Compile
 }
 
 // Compile compiles a Config into a Compiled configuration.
@@ -41,48 +77,176 @@ func Compile(cfg *Config) (*Compiled, error) {
 	}
 
 	compiled := &Compiled{
-		Flags: make(map[string]*CompiledFlag, len(cfg.Flags)),
+		Flags:    make(map[string]*CompiledFlag, len(cfg.Flags)),
+		Segments: make(map[string]*CompiledSegment, len(cfg.Segments)),
+	}
+
+	// Segments are compiled once and shared by every rule that refers to them
+	for name, segment := range cfg.Segments {
+		compiledSegment, err := compileSegment(name, &segment)
+		if err != nil {
+			return nil, fmt.Errorf("compile segment %q: %w", name, err)
+		}
+		compiled.Segments[name] = compiledSegment
 	}
 
 	for flagKey, flag := range cfg.Flags {
-		compiledFlag, err := compileFlag(flagKey, &flag)
+		compiledFlag, err := compileFlag(flagKey, &flag, compiled.Segments)
 		if err != nil {
 			return nil, fmt.Errorf("compile flag %q: %w", flagKey, err)
 		}
 		compiled.Flags[flagKey] = compiledFlag
 	}
 
+	if err := checkPrerequisites(cfg.Flags); err != nil {
+		return nil, err
+	}
+	linkPrerequisites(cfg, compiled)
+
 	return compiled, nil
 }
 
-func compileFlag(flagKey string, flag *Flag) (*CompiledFlag, error) {
+func compileFlag(flagKey string, flag *Flag, segments map[string]*CompiledSegment) (*CompiledFlag, error) {
 	compiledFlag := &CompiledFlag{
-		Enabled:  flag.Enabled,
-		Type:     flag.Type,
-		Variants: make(map[string]int, len(flag.Variants)),
-		Rules:    make([]*CompiledRule, 0, len(flag.Rules)),
-		Default:  flag.Default,
+		Enabled:    flag.Enabled,
+		Type:       flag.Type,
+		Variants:   make(map[string]int, len(flag.Variants)),
+		Values:     make(map[string]any, len(flag.Variants)),
+		Rules:      make([]*CompiledRule, 0, len(flag.Rules)),
+		Tags:       append([]string(nil), flag.Tags...),
+		ClientSide: flag.ClientSide,
+	}
+
+	if flag.Type == "json" {
+		if err := compiledFlag.compilePayloads(flag.Values); err != nil {
+			return nil, err
+		}
+	}
+
+	if flag.Default != nil {
+		def, ok := NormalizeValue(flag.Type, flag.Default)
+		if !ok {
+			return nil, fmt.Errorf("default must be %s, got %T", flag.Type, flag.Default)
+		}
+		compiledFlag.Default = def
 	}
 
 	// Copy variants
 	for k, v := range flag.Variants {
 		compiledFlag.Variants[k] = v
+		if err := compiledFlag.addValue(k); err != nil {
+			return nil, err
+		}
+	}
+
+	for k := range flag.Weights {
+		if err := compiledFlag.addValue(k); err != nil {
+			return nil, err
+		}
+	}
+	compiledFlag.Distribution = newSplit(flag.Variants, flag.Weights)
+	compiledFlag.Salt = HashSalt(flag.Salt)
+	compiledFlag.BucketBy = flag.BucketBy
+	compiledFlag.Sticky = flag.Sticky
+	compiledFlag.Rollout = compileRollout(flag.Rollout, flag.Variants, flag.Weights)
+
+	if len(flag.Targets) > 0 {
+		compiledFlag.Targets = make(map[string]string)
+		for variant, keys := range flag.Targets {
+			if err := compiledFlag.addValue(variant); err != nil {
+				return nil, fmt.Errorf("targets: %w", err)
+			}
+			for _, key := range keys {
+				compiledFlag.Targets[key] = variant
+			}
+		}
+	}
+
+	schedule, err := compileSchedule(&flag.Schedule)
+	if err != nil {
+		return nil, err
 	}
+	compiledFlag.Schedule = schedule
 
 	// Compile rules
 	for i, rule := range flag.Rules {
-		compiledRule, err := compileRule(&rule)
+		compiledRule, err := compileRule(&rule, segments)
 		if err != nil {
 			return nil, fmt.Errorf("compile rule %d: %w", i, err)
 		}
+		if rule.Then.Salt == "" {
+			compiledRule.Salt = compiledFlag.Salt
+		}
+		if rule.Then.BucketBy == "" {
+			compiledRule.BucketBy = compiledFlag.BucketBy
+		}
+		for k := range splitOf(rule.Then.Variants, rule.Then.Weights) {
+			if err := compiledFlag.addValue(k); err != nil {
+				return nil, fmt.Errorf("compile rule %d: %w", i, err)
+			}
+		}
 		compiledFlag.Rules = append(compiledFlag.Rules, compiledRule)
 	}
 
 	return compiledFlag, nil
 }
 
-func compileRule(rule *Rule) (*CompiledRule, error) {
+// compilePayloads decodes and encodes the values of a json flag once so that
+// evaluation only has to hand out the prepared forms.
+func (f *CompiledFlag) compilePayloads(values map[string]any) error {
+	f.Payloads = make(map[string]json.RawMessage, len(values))
+	for name, value := range values {
+		normalized, err := NormalizeJSON(value)
+		if err != nil {
+			return fmt.Errorf("value %q: %w", name, err)
+		}
+		payload, err := json.Marshal(normalized)
+		if err != nil {
+			return fmt.Errorf("encode value %q: %w", name, err)
+		}
+		f.Values[name] = normalized
+		f.Payloads[name] = payload
+	}
+	return nil
+}
+
+// HashSalt converts a configured salt into the value mixed into bucket
+// hashes. The empty salt hashes to 0, which leaves bucketing unchanged.
+func HashSalt(salt string) uint64 {
+	if salt == "" {
+		return 0
+	}
+	return xxhash.Sum64String(salt)
+}
+
+// newSplit builds the distribution for a split given either as percentages or
+// as bucket weights.
+func newSplit(variants, weights map[string]int) *Distribution {
+	if len(weights) > 0 {
+		return NewDistribution(weights, 1)
+	}
+	return NewDistribution(variants, BucketsPerPercent)
+}
+
+// addValue parses a variant name into its typed value.
+func (f *CompiledFlag) addValue(variant string) error {
+	if _, ok := f.Values[variant]; ok {
+		return nil
+	}
+	if f.Type == "json" {
+		return fmt.Errorf("json variant %q has no value", variant)
+	}
+	value, ok := ParseVariant(f.Type, variant)
+	if !ok {
+		return fmt.Errorf("invalid %s variant %q", f.Type, variant)
+	}
+	f.Values[variant] = value
+	return nil
+}
+
+func compileRule(rule *Rule, segments map[string]*CompiledSegment) (*CompiledRule, error) {
 	compiledRule := &CompiledRule{
+		ID:       rule.ID,
 		Variants: make(map[string]int, len(rule.Then.Variants)),
 	}
 
@@ -90,43 +254,67 @@ func compileRule(rule *Rule) (*CompiledRule, error) {
 	for k, v := range rule.Then.Variants {
 		compiledRule.Variants[k] = v
 	}
+	compiledRule.Distribution = newSplit(rule.Then.Variants, rule.Then.Weights)
+	compiledRule.Salt = HashSalt(rule.Then.Salt)
+	compiledRule.BucketBy = rule.Then.BucketBy
+	compiledRule.Rollout = compileRollout(rule.Then.Rollout, rule.Then.Variants, rule.Then.Weights)
 
-	// Compile conditions
-	var conditions []*CompiledCondition
-	isAll := len(rule.When.All) > 0
+	schedule, err := compileSchedule(&rule.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	compiledRule.Schedule = schedule
 
-	if isAll {
-		conditions = make([]*CompiledCondition, 0, len(rule.When.All))
-		for _, cond := range rule.When.All {
-			compiledCond, err := compileCondition(&cond, true)
-			if err != nil {
-				return nil, err
-			}
-			conditions = append(conditions, compiledCond)
-		}
-	} else {
-		conditions = make([]*CompiledCondition, 0, len(rule.When.Any))
-		for _, cond := range rule.When.Any {
-			compiledCond, err := compileCondition(&cond, false)
-			if err != nil {
-				return nil, err
-			}
-			conditions = append(conditions, compiledCond)
+	if rule.Segment != "" {
+		segment, ok := segments[rule.Segment]
+		if !ok {
+			return nil, fmt.Errorf("unknown segment %q", rule.Segment)
 		}
+		compiledRule.Segment = segment
+	}
+
+	when, err := compileWhen(&rule.When)
+	if err != nil {
+		return nil, err
 	}
+	compiledRule.When = when
+	compiledRule.Conditions = flatConditions(when)
 
-	compiledRule.Conditions = conditions
+	if rule.Expr != "" {
+		program, err := expr.Compile(rule.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("expr: %w", err)
+		}
+		compiledRule.Expr = program
+	}
 	return compiledRule, nil
 }
 
 func compileCondition(cond *AttributeCondition, isAll bool) (*CompiledCondition, error) {
+	code, ok := LookupOp(cond.Op)
+	if !ok {
+		return nil, fmt.Errorf("invalid operator %q", cond.Op)
+	}
+	value, err := CompileOperand(cond.Op, cond.Value)
+	if err != nil {
+		return nil, err
+	}
 	compiled := &CompiledCondition{
 		Attr:  cond.Attr,
 		Op:    cond.Op,
-		Value: cond.Value,
+		Code:  code,
+		Value: value,
 		IsAll: isAll,
 	}
 
+	if IsPath(cond.Attr) {
+		path, err := ParsePath(cond.Attr)
+		if err != nil {
+			return nil, err
+		}
+		compiled.Path = path
+	}
+
 	// Compile regex for "matches" operator
 	if cond.Op == "matches" {
 		pattern, ok := cond.Value.(string)
@@ -2,6 +2,9 @@ package config
 
 import (
 	"testing"
+
+	"github.com/0mjs/goff/internal/cidr"
+	"github.com/0mjs/goff/internal/semver"
 )
 
 func TestCompile(t *testing.T) {
@@ -103,6 +106,122 @@ func TestCompile_WithRegex(t *testing.T) {
 	}
 }
 
+func TestCompile_NumericValues(t *testing.T) {
+	cfg := &Config{
+		Version: 1,
+		Flags: map[string]Flag{
+			"limit": {
+				Type:     "int",
+				Variants: map[string]int{"10": 50, "20": 50},
+				Default:  5,
+			},
+			"ratio": {
+				Type:     "float",
+				Variants: map[string]int{"0.25": 100},
+				Default:  1,
+			},
+		},
+	}
+
+	compiled, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	limit := compiled.Flags["limit"]
+	if limit.Default != int64(5) {
+		t.Errorf("int Default = %#v, want int64(5)", limit.Default)
+	}
+	if limit.Values["20"] != int64(20) {
+		t.Errorf("int Values[\"20\"] = %#v, want int64(20)", limit.Values["20"])
+	}
+
+	ratio := compiled.Flags["ratio"]
+	if ratio.Default != float64(1) {
+		t.Errorf("float Default = %#v, want float64(1)", ratio.Default)
+	}
+	if ratio.Values["0.25"] != 0.25 {
+		t.Errorf("float Values[\"0.25\"] = %#v, want 0.25", ratio.Values["0.25"])
+	}
+}
+
+func TestCompile_JSONPayloads(t *testing.T) {
+	cfg := &Config{
+		Version: 1,
+		Flags: map[string]Flag{
+			"pricing": {
+				Type: "json",
+				Values: map[string]any{
+					"base": map[string]any{"price": 10, "tiers": []any{"a", "b"}},
+				},
+				Variants: map[string]int{"base": 100},
+				Default:  "base",
+			},
+		},
+	}
+
+	compiled, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	flag := compiled.Flags["pricing"]
+	if got := string(flag.Payloads["base"]); got != `{"price":10,"tiers":["a","b"]}` {
+		t.Errorf("Payloads[\"base\"] = %s", got)
+	}
+	if _, ok := flag.Values["base"].(map[string]any); !ok {
+		t.Errorf("Values[\"base\"] = %#v, want decoded object", flag.Values["base"])
+	}
+	if flag.Default != "base" {
+		t.Errorf("Default = %#v, want \"base\"", flag.Default)
+	}
+}
+
+func TestCompile_Bucketing(t *testing.T) {
+	cfg := &Config{
+		Version: 1,
+		Flags: map[string]Flag{
+			"rollout": {
+				Enabled:  true,
+				Type:     "bool",
+				Salt:     "s1",
+				BucketBy: "org_id",
+				Variants: map[string]int{"true": 10, "false": 90},
+				Rules: []Rule{
+					{
+						When: WhenCondition{All: []AttributeCondition{{Attr: "plan", Op: "eq", Value: "pro"}}},
+						Then: ThenAction{Variants: map[string]int{"true": 50, "false": 50}},
+					},
+					{
+						When: WhenCondition{All: []AttributeCondition{{Attr: "plan", Op: "eq", Value: "free"}}},
+						Then: ThenAction{Variants: map[string]int{"true": 50, "false": 50}, Salt: "s2", BucketBy: "team_id"},
+					},
+				},
+				Default: false,
+			},
+		},
+	}
+
+	compiled, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	flag := compiled.Flags["rollout"]
+	if flag.Salt != HashSalt("s1") || flag.Salt == 0 || flag.BucketBy != "org_id" {
+		t.Errorf("flag bucketing = %d, %q", flag.Salt, flag.BucketBy)
+	}
+	if r := flag.Rules[0]; r.Salt != flag.Salt || r.BucketBy != "org_id" {
+		t.Errorf("rule 0 should inherit flag bucketing, got %d, %q", r.Salt, r.BucketBy)
+	}
+	if r := flag.Rules[1]; r.Salt != HashSalt("s2") || r.BucketBy != "team_id" {
+		t.Errorf("rule 1 should override flag bucketing, got %d, %q", r.Salt, r.BucketBy)
+	}
+	if HashSalt("") != 0 {
+		t.Error("empty salt should hash to 0")
+	}
+}
+
 func TestCompile_NilConfig(t *testing.T) {
 	_, err := Compile(nil)
 	if err == nil {
@@ -110,3 +229,71 @@ func TestCompile_NilConfig(t *testing.T) {
 	}
 }
 
+func TestCompile_SemverValues(t *testing.T) {
+	cfg := &Config{
+		Version: 1,
+		Flags: map[string]Flag{
+			"test": {
+				Type: "bool",
+				Rules: []Rule{
+					{
+						When: WhenCondition{
+							All: []AttributeCondition{
+								{Attr: "app_version", Op: "semver_gte", Value: "v1.10.0"},
+								{Attr: "app_version", Op: "semver_in_range", Value: ">=1 <2"},
+							},
+						},
+						Then: ThenAction{Variants: map[string]int{"true": 100}},
+					},
+				},
+			},
+		},
+	}
+
+	compiled, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	conds := compiled.Flags["test"].Rules[0].Conditions
+	if v, ok := conds[0].Value.(semver.Version); !ok || v.String() != "1.10.0" {
+		t.Errorf("semver_gte value = %#v, want parsed version 1.10.0", conds[0].Value)
+	}
+	if _, ok := conds[1].Value.(semver.Constraint); !ok {
+		t.Errorf("semver_in_range value = %#v, want parsed constraint", conds[1].Value)
+	}
+}
+
+func TestCompile_CIDRValues(t *testing.T) {
+	cfg := &Config{
+		Version: 1,
+		Flags: map[string]Flag{
+			"test": {
+				Type: "bool",
+				Rules: []Rule{
+					{
+						When: WhenCondition{
+							All: []AttributeCondition{
+								{Attr: "ip", Op: "ip_in_cidr", Value: []any{"10.0.0.0/8", "10.1.0.0/16", "2001:db8::/32"}},
+							},
+						},
+						Then: ThenAction{Variants: map[string]int{"true": 100}},
+					},
+				},
+			},
+		},
+	}
+
+	compiled, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	set, ok := compiled.Flags["test"].Rules[0].Conditions[0].Value.(*cidr.Set)
+	if !ok {
+		t.Fatalf("ip_in_cidr value = %#v, want *cidr.Set", compiled.Flags["test"].Rules[0].Conditions[0].Value)
+	}
+	if set.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", set.Len())
+	}
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/0mjs/goff/internal/cidr"
+)
+
+// CompiledNode is a node of a compiled condition tree. Exactly one of
+// Condition, All, Any and Not is set.
+type CompiledNode struct {
+	Condition *CompiledCondition
+	All       []*CompiledNode
+	Any       []*CompiledNode
+	Not       *CompiledNode
+}
+
+// Validate checks the conditions for errors. Having none is valid.
+func (w *WhenCondition) Validate() error {
+	if err := validateConditions(w.All, ""); err != nil {
+		return err
+	}
+	if err := validateConditions(w.Any, ""); err != nil {
+		return err
+	}
+	if w.Not != nil {
+		if err := w.Not.validate("not"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateConditions(conditions []AttributeCondition, prefix string) error {
+	for i, cond := range conditions {
+		if err := cond.validate(fmt.Sprintf("%s%d", prefix, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks a condition or group; path locates it in error messages.
+func (c *AttributeCondition) validate(path string) error {
+	groups := 0
+	if len(c.All) > 0 {
+		groups++
+	}
+	if len(c.Any) > 0 {
+		groups++
+	}
+	if c.Not != nil {
+		groups++
+	}
+
+	if groups > 0 {
+		if c.Attr != "" || c.Op != "" || c.Value != nil {
+			return fmt.Errorf("condition %s: cannot mix 'attr' with 'all', 'any' or 'not'", path)
+		}
+		if groups > 1 {
+			return fmt.Errorf("condition %s: only one of 'all', 'any' or 'not' is allowed per group", path)
+		}
+		if c.Not != nil {
+			return c.Not.validate(path + ".not")
+		}
+		if err := validateConditions(c.All, path+".all."); err != nil {
+			return err
+		}
+		return validateConditions(c.Any, path+".any.")
+	}
+
+	if c.Attr == "" {
+		return fmt.Errorf("condition %s: attr is required", path)
+	}
+	if IsPath(c.Attr) {
+		if _, err := ParsePath(c.Attr); err != nil {
+			return fmt.Errorf("condition %s: %w", path, err)
+		}
+	}
+	if _, ok := LookupOp(c.Op); !ok {
+		return fmt.Errorf("condition %s: invalid operator %q", path, c.Op)
+	}
+	if c.Op == "matches" {
+		// Validate regex at parse time
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return fmt.Errorf("condition %s: 'matches' operator requires string value", path)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("condition %s: invalid regex pattern %q: %w", path, pattern, err)
+		}
+	}
+	if (c.Op == "exists" || c.Op == "not_exists") && c.Value != nil {
+		return fmt.Errorf("condition %s: '%s' operator takes no value", path, c.Op)
+	}
+	if _, err := CompileOperand(c.Op, c.Value); err != nil {
+		return fmt.Errorf("condition %s: %w", path, err)
+	}
+	return nil
+}
+
+// ParseCIDRs parses the value of an ip_in_cidr condition, a CIDR string or a
+// list of them.
+func ParseCIDRs(value any) (*cidr.Set, error) {
+	switch v := value.(type) {
+	case string:
+		return cidr.ParseSet([]string{v})
+	case []any:
+		cidrs := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("'ip_in_cidr' operator requires CIDR strings, got %T", item)
+			}
+			cidrs = append(cidrs, s)
+		}
+		return cidr.ParseSet(cidrs)
+	}
+	return nil, fmt.Errorf("'ip_in_cidr' operator requires a CIDR or a list of CIDRs, got %T", value)
+}
+
+// compileWhen compiles the conditions of a rule or segment into a tree.
+// Returns nil if there are none.
+func compileWhen(when *WhenCondition) (*CompiledNode, error) {
+	var parts []*CompiledNode
+	if len(when.All) > 0 {
+		node, err := compileGroup(when.All, true)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, node)
+	}
+	if len(when.Any) > 0 {
+		node, err := compileGroup(when.Any, false)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, node)
+	}
+	if when.Not != nil {
+		node, err := compileNode(when.Not, true)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, &CompiledNode{Not: node})
+	}
+
+	switch len(parts) {
+	case 0:
+		return nil, nil
+	case 1:
+		return parts[0], nil
+	}
+	return &CompiledNode{All: parts}, nil
+}
+
+// compileGroup compiles an all (isAll) or any list.
+func compileGroup(conditions []AttributeCondition, isAll bool) (*CompiledNode, error) {
+	children := make([]*CompiledNode, 0, len(conditions))
+	for _, cond := range conditions {
+		child, err := compileNode(&cond, isAll)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if isAll {
+		return &CompiledNode{All: children}, nil
+	}
+	return &CompiledNode{Any: children}, nil
+}
+
+// compileNode compiles a condition or nested group. isAll records, for
+// leaves, whether the enclosing group is an all.
+func compileNode(cond *AttributeCondition, isAll bool) (*CompiledNode, error) {
+	switch {
+	case len(cond.All) > 0:
+		return compileGroup(cond.All, true)
+	case len(cond.Any) > 0:
+		return compileGroup(cond.Any, false)
+	case cond.Not != nil:
+		child, err := compileNode(cond.Not, true)
+		if err != nil {
+			return nil, err
+		}
+		return &CompiledNode{Not: child}, nil
+	}
+
+	leaf, err := compileCondition(cond, isAll)
+	if err != nil {
+		return nil, err
+	}
+	return &CompiledNode{Condition: leaf}, nil
+}
+
+// flatConditions returns the leaves of a v1 rule, a single all or any group
+// of plain conditions, and nil for anything else.
+func flatConditions(node *CompiledNode) []*CompiledCondition {
+	if node == nil {
+		return nil
+	}
+	group := node.All
+	if len(group) == 0 {
+		group = node.Any
+	}
+	conditions := make([]*CompiledCondition, 0, len(group))
+	for _, child := range group {
+		if child.Condition == nil {
+			return nil
+		}
+		conditions = append(conditions, child.Condition)
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+	return conditions
+}
@@ -2,46 +2,85 @@ package config
 
 import (
 	"fmt"
-	"regexp"
+
+	"github.com/0mjs/goff/internal/expr"
 )
 
+// validTypes lists the supported flag types.
+var validTypes = map[string]bool{
+	"bool":   true,
+	"string": true,
+	"int":    true,
+	"float":  true,
+	"json":   true,
+}
+
 // Config represents the root configuration structure.
 type Config struct {
-	Version int             `yaml:"version"`
-	Flags   map[string]Flag `yaml:"flags"`
+	Version  int                `yaml:"version"`
+	Flags    map[string]Flag    `yaml:"flags"`
+	Segments map[string]Segment `yaml:"segments,omitempty"` // named audiences rules can refer to
 }
 
 // Flag represents a single feature flag.
 type Flag struct {
-	Enabled  bool           `yaml:"enabled"`
-	Type     string         `yaml:"type"`     // "bool" | "string"
-	Variants map[string]int `yaml:"variants"` // For bool: "true"/"false" with 0-100 percentages; for string: variant names with percentages
-	Rules    []Rule         `yaml:"rules,omitempty"`
-	Default  any            `yaml:"default"` // bool for bool flags, string for string flags
+	Enabled    bool             `yaml:"enabled"`
+	Type       string           `yaml:"type"`              // "bool" | "string" | "int" | "float" | "json"
+	Variants   map[string]int   `yaml:"variants"`          // variant -> percentage (0-100); for json flags, names from Values
+	Weights    map[string]int   `yaml:"weights,omitempty"` // alternative to Variants: variant -> buckets out of BucketCount
+	Values     map[string]any   `yaml:"values,omitempty"`  // json only: variant name -> arbitrary YAML/JSON payload
+	Schema     map[string]any   `yaml:"schema,omitempty"`  // json only: optional JSON Schema every value must satisfy
+	Rules      []Rule           `yaml:"rules,omitempty"`
+	Default    any              `yaml:"default"`               // value of the flag type; for json: name of a value
+	Tags       []string         `yaml:"tags,omitempty"`        // free-form labels for filtering, e.g. in AllFlags
+	ClientSide bool             `yaml:"client_side,omitempty"` // safe to expose to browsers and mobile apps
+	Salt       string           `yaml:"salt,omitempty"`        // changes which contexts land in which variant
+	BucketBy   string           `yaml:"bucket_by,omitempty"`   // context attribute to bucket by instead of the key
+	Sticky     bool             `yaml:"sticky,omitempty"`      // keep contexts on their variant when the split changes
+	Rollout    *Rollout         `yaml:"rollout,omitempty"`     // ramps one variant of the split up over time
+	Schedule   `yaml:",inline"` // when the flag is active; outside it the default is served
+
+	Prerequisites []Prerequisite      `yaml:"prerequisites,omitempty"` // flags that must evaluate to a variant first
+	Targets       map[string][]string `yaml:"targets,omitempty"`       // variant -> context keys served it before any rule
 }
 
 // Rule represents a targeting rule for a flag.
 type Rule struct {
-	When WhenCondition `yaml:"when"`
-	Then ThenAction    `yaml:"then"`
+	ID       string           `yaml:"id,omitempty"` // stable name of the rule; keys its sticky assignments
+	When     WhenCondition    `yaml:"when"`
+	Expr     string           `yaml:"expr,omitempty"`    // alternative to When: an expression over context attributes
+	Segment  string           `yaml:"segment,omitempty"` // name of a segment the context must belong to
+	Then     ThenAction       `yaml:"then"`
+	Schedule `yaml:",inline"` // when the rule applies; outside it the rule is skipped
 }
 
-// WhenCondition represents the condition to match.
+// WhenCondition represents the condition to match. When more than one of
+// All, Any and Not is given, all of them must match.
 type WhenCondition struct {
 	All []AttributeCondition `yaml:"all,omitempty"`
 	Any []AttributeCondition `yaml:"any,omitempty"`
+	Not *AttributeCondition  `yaml:"not,omitempty"`
 }
 
-// AttributeCondition represents a single attribute condition.
+// AttributeCondition represents a single attribute condition, or a nested
+// group when exactly one of All, Any or Not is set instead.
 type AttributeCondition struct {
-	Attr  string `yaml:"attr"`
-	Op    string `yaml:"op"` // eq | neq | gt | gte | lt | lte | in | contains | matches
-	Value any    `yaml:"value"`
+	Attr  string `yaml:"attr,omitempty"`
+	Op    string `yaml:"op,omitempty"` // see validOps
+	Value any    `yaml:"value,omitempty"`
+
+	All []AttributeCondition `yaml:"all,omitempty"`
+	Any []AttributeCondition `yaml:"any,omitempty"`
+	Not *AttributeCondition  `yaml:"not,omitempty"`
 }
 
 // ThenAction represents the action to take when a rule matches.
 type ThenAction struct {
-	Variants map[string]int `yaml:"variants"` // Same format as Flag.Variants
+	Variants map[string]int `yaml:"variants"`            // Same format as Flag.Variants
+	Weights  map[string]int `yaml:"weights,omitempty"`   // Same format as Flag.Weights
+	Salt     string         `yaml:"salt,omitempty"`      // overrides Flag.Salt for this rule's split
+	BucketBy string         `yaml:"bucket_by,omitempty"` // overrides Flag.BucketBy for this rule's split
+	Rollout  *Rollout       `yaml:"rollout,omitempty"`   // Same format as Flag.Rollout
 }
 
 // Validate checks the configuration for errors.
@@ -54,107 +93,94 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("no flags defined")
 	}
 
+	for name, segment := range c.Segments {
+		if err := segment.Validate(); err != nil {
+			return fmt.Errorf("segment %q: %w", name, err)
+		}
+	}
+
 	for flagKey, flag := range c.Flags {
 		if err := flag.Validate(flagKey); err != nil {
 			return fmt.Errorf("flag %q: %w", flagKey, err)
 		}
+		for i, rule := range flag.Rules {
+			if _, ok := c.Segments[rule.Segment]; rule.Segment != "" && !ok {
+				return fmt.Errorf("flag %q: rule %d: unknown segment %q", flagKey, i, rule.Segment)
+			}
+		}
 	}
 
-	return nil
+	return checkPrerequisites(c.Flags)
 }
 
 // Validate checks a flag for errors.
 func (f *Flag) Validate(flagKey string) error {
-	if f.Type != "bool" && f.Type != "string" {
-		return fmt.Errorf("invalid type %q (must be 'bool' or 'string')", f.Type)
+	if !validTypes[f.Type] {
+		return fmt.Errorf("invalid type %q (must be 'bool', 'string', 'int', 'float' or 'json')", f.Type)
 	}
 
-	if f.Type == "bool" {
-		if f.Default != nil {
-			if _, ok := f.Default.(bool); !ok {
-				return fmt.Errorf("default must be bool for bool flags, got %T", f.Default)
-			}
+	if f.Type == "json" {
+		if err := f.validateValues(); err != nil {
+			return err
 		}
-		// Validate bool variants
-		if len(f.Variants) > 0 {
-			total := 0
-			hasTrue := false
-			hasFalse := false
-			for k, v := range f.Variants {
-				if k != "true" && k != "false" {
-					return fmt.Errorf("bool flag variants must be 'true' or 'false', got %q", k)
-				}
-				if k == "true" {
-					hasTrue = true
-				}
-				if k == "false" {
-					hasFalse = true
-				}
-				if v < 0 || v > 100 {
-					return fmt.Errorf("variant %q percentage must be 0-100, got %d", k, v)
-				}
-				total += v
-			}
-			if !hasTrue || !hasFalse {
-				return fmt.Errorf("bool flag must have both 'true' and 'false' variants")
-			}
-			if total != 100 {
-				return fmt.Errorf("bool flag variant percentages must sum to 100, got %d", total)
-			}
+	} else if len(f.Values) > 0 || f.Schema != nil {
+		return fmt.Errorf("values and schema are only supported for json flags")
+	}
+
+	if f.Default != nil {
+		if err := f.validateDefault(); err != nil {
+			return err
 		}
-	} else {
-		// string type
-		if f.Default != nil {
-			if _, ok := f.Default.(string); !ok {
-				return fmt.Errorf("default must be string for string flags, got %T", f.Default)
-			}
+	}
+
+	if _, err := compileSchedule(&f.Schedule); err != nil {
+		return err
+	}
+
+	if err := f.validateSplit(f.Variants, f.Weights); err != nil {
+		return err
+	}
+	if split := splitOf(f.Variants, f.Weights); len(split) > 0 && f.Type == "bool" {
+		_, hasTrue := split["true"]
+		_, hasFalse := split["false"]
+		if !hasTrue || !hasFalse {
+			return fmt.Errorf("bool flag must have both 'true' and 'false' variants")
 		}
-		if len(f.Variants) > 0 {
-			total := 0
-			for k, v := range f.Variants {
-				if v < 0 || v > 100 {
-					return fmt.Errorf("variant %q percentage must be 0-100, got %d", k, v)
-				}
-				total += v
-			}
-			if total != 100 {
-				return fmt.Errorf("string flag variant percentages must sum to 100, got %d", total)
-			}
+	}
+	if f.Rollout != nil {
+		if err := f.validateRollout(f.Rollout, f.Variants, f.Weights); err != nil {
+			return err
 		}
 	}
 
+	if err := f.validateTargets(); err != nil {
+		return err
+	}
+
 	// Validate rules
+	ids := make(map[string]int)
 	for i, rule := range f.Rules {
 		if err := rule.Validate(); err != nil {
 			return fmt.Errorf("rule %d: %w", i, err)
 		}
+		if rule.ID != "" {
+			if j, ok := ids[rule.ID]; ok {
+				return fmt.Errorf("rule %d: id %q is already used by rule %d", i, rule.ID, j)
+			}
+			ids[rule.ID] = i
+		}
+		// Sticky assignments are kept per rule, so they need a name that
+		// survives rules being reordered
+		if f.Sticky && rule.ID == "" && rule.Then.splits() {
+			return fmt.Errorf("rule %d: rules of sticky flags that split traffic need an 'id'", i)
+		}
 		// Validate rule variants
-		if len(rule.Then.Variants) > 0 {
-			if f.Type == "bool" {
-				total := 0
-				for k, v := range rule.Then.Variants {
-					if k != "true" && k != "false" {
-						return fmt.Errorf("rule %d: bool flag variants must be 'true' or 'false', got %q", i, k)
-					}
-					if v < 0 || v > 100 {
-						return fmt.Errorf("rule %d: variant %q percentage must be 0-100, got %d", i, k, v)
-					}
-					total += v
-				}
-				if total != 100 {
-					return fmt.Errorf("rule %d: bool flag variant percentages must sum to 100, got %d", i, total)
-				}
-			} else {
-				total := 0
-				for k, v := range rule.Then.Variants {
-					if v < 0 || v > 100 {
-						return fmt.Errorf("rule %d: variant %q percentage must be 0-100, got %d", i, k, v)
-					}
-					total += v
-				}
-				if total != 100 {
-					return fmt.Errorf("rule %d: string flag variant percentages must sum to 100, got %d", i, total)
-				}
+		if err := f.validateSplit(rule.Then.Variants, rule.Then.Weights); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		if rule.Then.Rollout != nil {
+			if err := f.validateRollout(rule.Then.Rollout, rule.Then.Variants, rule.Then.Weights); err != nil {
+				return fmt.Errorf("rule %d: %w", i, err)
 			}
 		}
 	}
@@ -162,58 +188,193 @@ func (f *Flag) Validate(flagKey string) error {
 	return nil
 }
 
-// Validate checks a rule for errors.
-func (r *Rule) Validate() error {
-	hasAll := len(r.When.All) > 0
-	hasAny := len(r.When.Any) > 0
+// validateDefault checks that the default is a value of the flag type.
+func (f *Flag) validateDefault() error {
+	if f.Type == "json" {
+		name, ok := f.Default.(string)
+		if !ok {
+			return fmt.Errorf("default must name a value for json flags, got %T", f.Default)
+		}
+		if _, ok := f.Values[name]; !ok {
+			return fmt.Errorf("default %q is not a defined value", name)
+		}
+		return nil
+	}
+	if _, ok := NormalizeValue(f.Type, f.Default); !ok {
+		return fmt.Errorf("default must be %s for %s flags, got %T", f.Type, f.Type, f.Default)
+	}
+	return nil
+}
 
-	if !hasAll && !hasAny {
-		return fmt.Errorf("rule must have 'all' or 'any' condition")
+// validateValues checks the payloads of a json flag against its schema.
+func (f *Flag) validateValues() error {
+	if len(f.Values) == 0 {
+		return fmt.Errorf("json flag must define values")
+	}
+	var schema map[string]any
+	if f.Schema != nil {
+		normalized, err := NormalizeJSON(f.Schema)
+		if err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+		schema = normalized.(map[string]any)
 	}
+	for name, value := range f.Values {
+		normalized, err := NormalizeJSON(value)
+		if err != nil {
+			return fmt.Errorf("value %q: %w", name, err)
+		}
+		if schema != nil {
+			if err := ValidateSchema(schema, normalized); err != nil {
+				return fmt.Errorf("value %q: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
 
-	if hasAll && hasAny {
-		return fmt.Errorf("rule cannot have both 'all' and 'any' conditions")
+// validateTargets checks target variants and that no key is targeted twice.
+func (f *Flag) validateTargets() error {
+	seen := make(map[string]string)
+	for variant, keys := range f.Targets {
+		if err := f.validateVariantName(variant); err != nil {
+			return fmt.Errorf("targets: %w", err)
+		}
+		for _, key := range keys {
+			if other, ok := seen[key]; ok && other != variant {
+				return fmt.Errorf("targets: key %q is targeted by both %q and %q", key, other, variant)
+			}
+			seen[key] = variant
+		}
 	}
+	return nil
+}
 
-	conditions := r.When.All
-	if hasAny {
-		conditions = r.When.Any
+// validateSplit checks a variant split given either as percentages or as
+// bucket weights.
+func (f *Flag) validateSplit(variants, weights map[string]int) error {
+	switch {
+	case len(variants) > 0 && len(weights) > 0:
+		return fmt.Errorf("cannot have both 'variants' and 'weights'")
+	case len(weights) > 0:
+		return f.validateWeights(weights)
+	case len(variants) > 0:
+		return f.validateVariants(variants)
 	}
+	return nil
+}
 
-	validOps := map[string]bool{
-		"eq":       true,
-		"neq":      true,
-		"gt":       true,
-		"gte":      true,
-		"lt":       true,
-		"lte":      true,
-		"in":       true,
-		"contains": true,
-		"matches":  true,
+// splitOf returns whichever of variants or weights is in use.
+func splitOf(variants, weights map[string]int) map[string]int {
+	if len(weights) > 0 {
+		return weights
 	}
+	return variants
+}
 
-	for i, cond := range conditions {
-		if cond.Attr == "" {
-			return fmt.Errorf("condition %d: attr is required", i)
+// validateWeights checks variant names against the flag type and that
+// weights are non-negative and sum to BucketCount.
+func (f *Flag) validateWeights(weights map[string]int) error {
+	total := 0
+	for k, v := range weights {
+		if err := f.validateVariantName(k); err != nil {
+			return err
 		}
-		if !validOps[cond.Op] {
-			return fmt.Errorf("condition %d: invalid operator %q", i, cond.Op)
+		if v < 0 || v > BucketCount {
+			return fmt.Errorf("variant %q weight must be 0-%d, got %d", k, BucketCount, v)
 		}
-		if cond.Op == "matches" {
-			// Validate regex at parse time
-			pattern, ok := cond.Value.(string)
-			if !ok {
-				return fmt.Errorf("condition %d: 'matches' operator requires string value", i)
-			}
-			if _, err := regexp.Compile(pattern); err != nil {
-				return fmt.Errorf("condition %d: invalid regex pattern %q: %w", i, pattern, err)
-			}
+		total += v
+	}
+	if total != BucketCount {
+		return fmt.Errorf("%s flag variant weights must sum to %d, got %d", f.Type, BucketCount, total)
+	}
+	return nil
+}
+
+// validateVariants checks variant names against the flag type and that
+// percentages are in range and sum to 100.
+func (f *Flag) validateVariants(variants map[string]int) error {
+	total := 0
+	for k, v := range variants {
+		if err := f.validateVariantName(k); err != nil {
+			return err
 		}
+		if v < 0 || v > 100 {
+			return fmt.Errorf("variant %q percentage must be 0-100, got %d", k, v)
+		}
+		total += v
+	}
+	if total != 100 {
+		return fmt.Errorf("%s flag variant percentages must sum to 100, got %d", f.Type, total)
 	}
+	return nil
+}
 
-	if len(r.Then.Variants) == 0 {
-		return fmt.Errorf("rule must have 'then.variants'")
+// validateVariantName checks that a variant name can be converted to a value
+// of the flag type.
+func (f *Flag) validateVariantName(name string) error {
+	switch f.Type {
+	case "json":
+		if _, ok := f.Values[name]; !ok {
+			return fmt.Errorf("json flag variant %q has no value", name)
+		}
+		return nil
+	case "bool":
+		if name != "true" && name != "false" {
+			return fmt.Errorf("bool flag variants must be 'true' or 'false', got %q", name)
+		}
+		return nil
+	}
+	if _, ok := ParseVariant(f.Type, name); !ok {
+		return fmt.Errorf("%s flag variant %q is not a valid %s", f.Type, name, f.Type)
+	}
+	return nil
+}
+
+// Validate checks a rule for errors.
+func (r *Rule) Validate() error {
+	if r.When.IsZero() && r.Expr == "" && r.Segment == "" {
+		return fmt.Errorf("rule must have 'all' or 'any' condition, an 'expr', or a 'segment'")
+	}
+	if !r.When.IsZero() && r.Expr != "" {
+		return fmt.Errorf("cannot have both 'when' and 'expr'")
+	}
+	if r.Expr != "" {
+		if _, err := expr.Compile(r.Expr); err != nil {
+			return fmt.Errorf("expr: %w", err)
+		}
+	}
+
+	if _, err := compileSchedule(&r.Schedule); err != nil {
+		return err
+	}
+
+	if err := r.When.Validate(); err != nil {
+		return err
+	}
+
+	if len(r.Then.Variants) == 0 && len(r.Then.Weights) == 0 {
+		return fmt.Errorf("rule must have 'then.variants' or 'then.weights'")
 	}
 
 	return nil
 }
+
+// IsZero reports whether no conditions are given.
+func (w *WhenCondition) IsZero() bool {
+	return len(w.All) == 0 && len(w.Any) == 0 && w.Not == nil
+}
+
+// splits reports whether the action can serve more than one variant.
+func (t *ThenAction) splits() bool {
+	if t.Rollout != nil {
+		return true
+	}
+	served := 0
+	for _, share := range splitOf(t.Variants, t.Weights) {
+		if share > 0 {
+			served++
+		}
+	}
+	return served > 1
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 )
 
 func TestConfigValidate(t *testing.T) {
@@ -146,6 +147,146 @@ func TestFlagValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid int flag",
+			flag: Flag{
+				Type: "int",
+				Variants: map[string]int{
+					"10":  50,
+					"-20": 50,
+				},
+				Default: 10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid float flag",
+			flag: Flag{
+				Type: "float",
+				Variants: map[string]int{
+					"0.5": 50,
+					"1":   50,
+				},
+				Default: 1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid int variant",
+			flag: Flag{
+				Type: "int",
+				Variants: map[string]int{
+					"1.5": 100,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid float variant",
+			flag: Flag{
+				Type: "float",
+				Variants: map[string]int{
+					"fast": 100,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid int rule variant",
+			flag: Flag{
+				Type: "int",
+				Rules: []Rule{
+					{
+						When: WhenCondition{
+							All: []AttributeCondition{{Attr: "plan", Op: "eq", Value: "pro"}},
+						},
+						Then: ThenAction{Variants: map[string]int{"many": 100}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid default type for int",
+			flag: Flag{
+				Type:    "int",
+				Default: 1.5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid default type for float",
+			flag: Flag{
+				Type:    "float",
+				Default: "1.5",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid json flag",
+			flag: Flag{
+				Type: "json",
+				Values: map[string]any{
+					"small": map[string]any{"limit": 10},
+					"large": map[string]any{"limit": 100},
+				},
+				Schema: map[string]any{
+					"type":     "object",
+					"required": []any{"limit"},
+				},
+				Variants: map[string]int{"small": 50, "large": 50},
+				Default:  "small",
+			},
+			wantErr: false,
+		},
+		{
+			name: "json flag without values",
+			flag: Flag{
+				Type: "json",
+			},
+			wantErr: true,
+		},
+		{
+			name: "json variant without value",
+			flag: Flag{
+				Type:     "json",
+				Values:   map[string]any{"small": map[string]any{"limit": 10}},
+				Variants: map[string]int{"small": 50, "large": 50},
+			},
+			wantErr: true,
+		},
+		{
+			name: "json default not a value",
+			flag: Flag{
+				Type:    "json",
+				Values:  map[string]any{"small": map[string]any{"limit": 10}},
+				Default: "large",
+			},
+			wantErr: true,
+		},
+		{
+			name: "json value violates schema",
+			flag: Flag{
+				Type: "json",
+				Values: map[string]any{
+					"small": map[string]any{"limit": "ten"},
+				},
+				Schema: map[string]any{
+					"properties": map[string]any{
+						"limit": map[string]any{"type": "integer"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "values on non-json flag",
+			flag: Flag{
+				Type:   "string",
+				Values: map[string]any{"a": 1},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid default type for bool",
 			flag: Flag{
@@ -162,6 +303,181 @@ func TestFlagValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid weights",
+			flag: Flag{
+				Type:    "string",
+				Weights: map[string]int{"canary": 1, "stable": 99999},
+				Default: "stable",
+			},
+			wantErr: false,
+		},
+		{
+			name: "weights must sum to bucket count",
+			flag: Flag{
+				Type:    "string",
+				Weights: map[string]int{"canary": 1, "stable": 99},
+				Default: "stable",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative weight",
+			flag: Flag{
+				Type:    "string",
+				Weights: map[string]int{"canary": -1, "stable": 100001},
+				Default: "stable",
+			},
+			wantErr: true,
+		},
+		{
+			name: "variants and weights together",
+			flag: Flag{
+				Type:     "string",
+				Variants: map[string]int{"stable": 100},
+				Weights:  map[string]int{"stable": 100000},
+				Default:  "stable",
+			},
+			wantErr: true,
+		},
+		{
+			name: "bool weights missing false",
+			flag: Flag{
+				Type:    "bool",
+				Weights: map[string]int{"true": 100000},
+				Default: false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid linear rollout",
+			flag: Flag{
+				Type:     "bool",
+				Variants: map[string]int{"true": 0, "false": 100},
+				Rollout: &Rollout{
+					Variant: "true",
+					Start:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+					End:     time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC),
+				},
+				Default: false,
+			},
+			wantErr: false,
+		},
+		{
+			name: "rollout end before start",
+			flag: Flag{
+				Type:     "bool",
+				Variants: map[string]int{"true": 0, "false": 100},
+				Rollout: &Rollout{
+					Variant: "true",
+					Start:   time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC),
+					End:     time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+				},
+				Default: false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "rollout to 0 below from",
+			flag: Flag{
+				Type:     "bool",
+				Variants: map[string]int{"true": 50, "false": 50},
+				Rollout: &Rollout{
+					Variant: "true",
+					Start:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+					End:     time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC),
+					From:    50,
+					To:      new(float64),
+				},
+				Default: false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "rollout steps must not decrease",
+			flag: Flag{
+				Type:     "bool",
+				Variants: map[string]int{"true": 0, "false": 100},
+				Rollout: &Rollout{
+					Variant: "true",
+					Steps: []RolloutStep{
+						{At: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Percent: 10},
+						{At: time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC), Percent: 5},
+					},
+				},
+				Default: false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "rollout variant not in split",
+			flag: Flag{
+				Type:     "string",
+				Variants: map[string]int{"a": 100},
+				Rollout: &Rollout{
+					Variant: "b",
+					Steps:   []RolloutStep{{At: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Percent: 10}},
+				},
+				Default: "a",
+			},
+			wantErr: true,
+		},
+		{
+			name: "rollout without split",
+			flag: Flag{
+				Type: "string",
+				Rollout: &Rollout{
+					Variant: "b",
+					Steps:   []RolloutStep{{At: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Percent: 10}},
+				},
+				Default: "a",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid targets",
+			flag: Flag{
+				Type:     "bool",
+				Variants: map[string]int{"true": 0, "false": 100},
+				Targets:  map[string][]string{"true": {"user:1", "user:2"}, "false": {"user:3"}},
+				Default:  false,
+			},
+			wantErr: false,
+		},
+		{
+			name: "key targeted by two variants",
+			flag: Flag{
+				Type:     "bool",
+				Variants: map[string]int{"true": 0, "false": 100},
+				Targets:  map[string][]string{"true": {"user:1"}, "false": {"user:1"}},
+				Default:  false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid target variant",
+			flag: Flag{
+				Type:     "int",
+				Variants: map[string]int{"1": 100},
+				Targets:  map[string][]string{"many": {"user:1"}},
+				Default:  1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "rule weights",
+			flag: Flag{
+				Type: "string",
+				Rules: []Rule{
+					{
+						When: WhenCondition{All: []AttributeCondition{{Attr: "plan", Op: "eq", Value: "pro"}}},
+						Then: ThenAction{Weights: map[string]int{"canary": 10, "stable": 99990}},
+					},
+				},
+				Default: "stable",
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -174,6 +490,41 @@ func TestFlagValidate(t *testing.T) {
 	}
 }
 
+func TestFlagValidate_RuleIDs(t *testing.T) {
+	rule := func(id string, variants map[string]int) Rule {
+		return Rule{
+			ID:   id,
+			When: WhenCondition{All: []AttributeCondition{{Attr: "plan", Op: "eq", Value: "pro"}}},
+			Then: ThenAction{Variants: variants},
+		}
+	}
+	split := map[string]int{"true": 50, "false": 50}
+	single := map[string]int{"true": 100, "false": 0}
+
+	tests := []struct {
+		name    string
+		sticky  bool
+		rules   []Rule
+		wantErr bool
+	}{
+		{"ids are optional", false, []Rule{rule("", split), rule("", split)}, false},
+		{"sticky with ids", true, []Rule{rule("a", split), rule("b", split)}, false},
+		{"sticky without id", true, []Rule{rule("a", split), rule("", split)}, true},
+		{"sticky single variant without id", true, []Rule{rule("", single)}, false},
+		{"duplicate ids", false, []Rule{rule("a", split), rule("a", single)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag := Flag{Enabled: true, Type: "bool", Sticky: tt.sticky, Variants: split, Rules: tt.rules}
+			err := flag.Validate("test")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Flag.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestRuleValidate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -224,6 +575,44 @@ func TestRuleValidate(t *testing.T) {
 					Variants: map[string]int{"true": 100},
 				},
 			},
+			wantErr: false, // both must match
+		},
+		{
+			name: "nested groups",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "plan", Op: "eq", Value: "pro"},
+						{Any: []AttributeCondition{
+							{Attr: "region", Op: "eq", Value: "us"},
+							{Not: &AttributeCondition{Attr: "beta", Op: "eq", Value: false}},
+						}},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "group mixed with attr",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "plan", Op: "eq", Value: "pro", Any: []AttributeCondition{{Attr: "a", Op: "eq", Value: "b"}}},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid nested operator",
+			rule: Rule{
+				When: WhenCondition{
+					Not: &AttributeCondition{Any: []AttributeCondition{{Attr: "a", Op: "like", Value: "b"}}},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
 			wantErr: true,
 		},
 		{
@@ -264,6 +653,196 @@ func TestRuleValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "semver operators",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "app_version", Op: "semver_gte", Value: "1.10.0"},
+						{Attr: "app_version", Op: "semver_in_range", Value: ">=2.3.0 <3"},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid semver version",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "app_version", Op: "semver_gt", Value: "1.x"},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "semver version not a string",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "app_version", Op: "semver_gt", Value: 1.1},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid semver range",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "app_version", Op: "semver_in_range", Value: ">=2.3.0 ||"},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "time operators",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "created_at", Op: "after", Value: "2026-01-01"},
+						{Attr: "trial_ends_at", Op: "before", Value: "now+7d"},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid time",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "created_at", Op: "after", Value: "last tuesday"},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "string and existence operators",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "email", Op: "ends_with", Value: "@example.com"},
+						{Attr: "region", Op: "not_in", Value: []any{"cn", "ru"}},
+						{Attr: "plan", Op: "in_ci", Value: []any{"pro"}},
+						{Attr: "beta", Op: "exists"},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "exists with value",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "beta", Op: "exists", Value: true},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "not_in without array",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "region", Op: "not_in", Value: "cn"},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ip_in_cidr",
+			rule: Rule{
+				When: WhenCondition{
+					Any: []AttributeCondition{
+						{Attr: "ip", Op: "ip_in_cidr", Value: "10.0.0.0/8"},
+						{Attr: "ip", Op: "ip_in_cidr", Value: []any{"192.168.0.0/16", "2001:db8::/32"}},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid cidr",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "ip", Op: "ip_in_cidr", Value: []any{"10.0.0.0/8", "10.0.0.0/33"}},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "set operators",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "roles", Op: "any_of", Value: []any{"admin", "billing"}},
+						{Attr: "groups", Op: "none_of", Value: []any{"banned"}},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "set operator without array",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{
+						{Attr: "roles", Op: "all_of", Value: "admin"},
+					},
+				},
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "expr",
+			rule: Rule{
+				Expr: `seats * price > 10000 && plan in ["pro", "ent"]`,
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid expr",
+			rule: Rule{
+				Expr: `seats * "price" > 10000`,
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "expr and when",
+			rule: Rule{
+				When: WhenCondition{
+					All: []AttributeCondition{{Attr: "plan", Op: "eq", Value: "pro"}},
+				},
+				Expr: `seats > 10`,
+				Then: ThenAction{Variants: map[string]int{"true": 100}},
+			},
+			wantErr: true,
+		},
 		{
 			name: "rule with no variants",
 			rule: Rule{
@@ -287,4 +866,3 @@ func TestRuleValidate(t *testing.T) {
 		})
 	}
 }
-
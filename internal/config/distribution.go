@@ -0,0 +1,116 @@
+package config
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	// BucketCount is the number of buckets contexts are hashed into for
+	// rollouts, allowing splits as fine as 0.001%.
+	BucketCount = 100000
+	// BucketsPerPercent is the number of buckets covered by one percent.
+	BucketsPerPercent = BucketCount / 100
+)
+
+// Distribution is a variant split precomputed at compile time so that
+// evaluation neither sorts nor allocates.
+type Distribution struct {
+	Variants []string // variant names in sorted order
+	Bounds   []int    // cumulative exclusive upper bucket bound for each variant
+	// Single is set when one variant covers every bucket, in which case the
+	// bucket does not need to be computed at all.
+	Single   string
+	IsSingle bool
+}
+
+// NewDistribution builds the cumulative bucket table for a variant split.
+// unit is the number of buckets per unit of weight: BucketsPerPercent for
+// percentages, 1 for bucket weights. Variants are ordered by name so that the
+// table, and therefore which users land in which variant, is deterministic.
+// Returns nil if there are no variants.
+func NewDistribution(weights map[string]int, unit int) *Distribution {
+	if len(weights) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dist := &Distribution{
+		Variants: names,
+		Bounds:   make([]int, len(names)),
+	}
+	cumulative := 0
+	for i, name := range names {
+		buckets := weights[name] * unit
+		if buckets >= BucketCount {
+			dist.Single = name
+			dist.IsSingle = true
+		}
+		cumulative += buckets
+		dist.Bounds[i] = cumulative
+	}
+
+	return dist
+}
+
+// Select returns the variant covering bucket, or false if the weights do not
+// reach it.
+func (d *Distribution) Select(bucket int) (string, bool) {
+	for i, bound := range d.Bounds {
+		if bucket < bound {
+			return d.Variants[i], true
+		}
+	}
+	return "", false
+}
+
+// Covers reports whether variant is part of the split with at least one
+// bucket.
+func (d *Distribution) Covers(variant string) bool {
+	i := sort.SearchStrings(d.Variants, variant)
+	if i == len(d.Variants) || d.Variants[i] != variant {
+		return false
+	}
+	lower := 0
+	if i > 0 {
+		lower = d.Bounds[i-1]
+	}
+	return d.Bounds[i] > lower
+}
+
+// lazyDistribution holds the bucket table of a flag or rule constructed
+// without Compile, built the first time it is evaluated.
+type lazyDistribution struct {
+	once sync.Once
+	dist *Distribution
+}
+
+func (l *lazyDistribution) get(variants map[string]int) *Distribution {
+	l.once.Do(func() {
+		l.dist = NewDistribution(variants, BucketsPerPercent)
+	})
+	return l.dist
+}
+
+// Split returns the flag's bucket table: Distribution if Compile set it,
+// otherwise one built once from Variants.
+func (f *CompiledFlag) Split() *Distribution {
+	if f.Distribution != nil || len(f.Variants) == 0 {
+		return f.Distribution
+	}
+	return f.lazy.get(f.Variants)
+}
+
+// Split returns the rule's bucket table: Distribution if Compile set it,
+// otherwise one built once from Variants.
+func (r *CompiledRule) Split() *Distribution {
+	if r.Distribution != nil || len(r.Variants) == 0 {
+		return r.Distribution
+	}
+	return r.lazy.get(r.Variants)
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// Number is a numeric value that keeps integers exact. Integers are compared
+// as integers rather than through float64, which cannot represent every
+// integer above 2^53.
+type Number struct {
+	kind numberKind
+	i    int64
+	u    uint64 // used for unsigned values above math.MaxInt64
+	f    float64
+}
+
+type numberKind uint8
+
+const (
+	numberInt numberKind = iota + 1
+	numberUint
+	numberFloat
+)
+
+// IntNumber returns the Number for an int64.
+func IntNumber(i int64) Number {
+	return Number{kind: numberInt, i: i}
+}
+
+// UintNumber returns the Number for a uint64.
+func UintNumber(u uint64) Number {
+	if u <= math.MaxInt64 {
+		return IntNumber(int64(u))
+	}
+	return Number{kind: numberUint, u: u}
+}
+
+// FloatNumber returns the Number for a float64. Integral values that fit an
+// int64 are stored as integers, so 1.0 and 1 are the same Number.
+func FloatNumber(f float64) Number {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return IntNumber(int64(f))
+	}
+	return Number{kind: numberFloat, f: f}
+}
+
+// ParseNumber converts a Go number, a json.Number or a numeric string to a
+// Number. Strings are read as integers when they are integers.
+func ParseNumber(v any) (Number, bool) {
+	switch n := v.(type) {
+	case int:
+		return IntNumber(int64(n)), true
+	case int8:
+		return IntNumber(int64(n)), true
+	case int16:
+		return IntNumber(int64(n)), true
+	case int32:
+		return IntNumber(int64(n)), true
+	case int64:
+		return IntNumber(n), true
+	case uint:
+		return UintNumber(uint64(n)), true
+	case uint8:
+		return UintNumber(uint64(n)), true
+	case uint16:
+		return UintNumber(uint64(n)), true
+	case uint32:
+		return UintNumber(uint64(n)), true
+	case uint64:
+		return UintNumber(n), true
+	case float32:
+		return FloatNumber(float64(n)), true
+	case float64:
+		return FloatNumber(n), true
+	case json.Number:
+		return parseNumberString(string(n))
+	case string:
+		return parseNumberString(n)
+	}
+	return Number{}, false
+}
+
+func parseNumberString(s string) (Number, bool) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return IntNumber(i), true
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return UintNumber(u), true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Number{}, false
+	}
+	return FloatNumber(f), true
+}
+
+// CompareNumbers returns -1, 0 or 1 as a is less than, equal to or greater
+// than b, exactly. ok is false if either is NaN, in which case they are
+// unordered.
+func CompareNumbers(a, b Number) (result int, ok bool) {
+	switch {
+	case a.kind == numberFloat && b.kind == numberFloat:
+		if math.IsNaN(a.f) || math.IsNaN(b.f) {
+			return 0, false
+		}
+		return cmp3(a.f < b.f, a.f > b.f), true
+	case a.kind == numberFloat:
+		c, ok := CompareNumbers(b, a)
+		return -c, ok
+	case b.kind == numberFloat:
+		if math.IsNaN(b.f) {
+			return 0, false
+		}
+		if a.kind == numberUint {
+			return compareUintFloat(a.u, b.f), true
+		}
+		return compareIntFloat(a.i, b.f), true
+	}
+
+	// Both integers; unsigned ones are above math.MaxInt64
+	switch {
+	case a.kind == numberUint && b.kind == numberUint:
+		return cmp3(a.u < b.u, a.u > b.u), true
+	case a.kind == numberUint:
+		return 1, true
+	case b.kind == numberUint:
+		return -1, true
+	}
+	return cmp3(a.i < b.i, a.i > b.i), true
+}
+
+// twoTo63 and twoTo64 are exact as float64.
+const (
+	twoTo63 = 1 << 63
+	twoTo64 = 1 << 64
+)
+
+func compareIntFloat(i int64, f float64) int {
+	switch {
+	case f >= twoTo63:
+		return -1
+	case f < -twoTo63:
+		return 1
+	}
+	// -2^63 <= f < 2^63, so its integer part converts exactly
+	t := math.Trunc(f)
+	if ti := int64(t); i != ti {
+		return cmp3(i < ti, i > ti)
+	}
+	return cmp3(f > t, f < t)
+}
+
+func compareUintFloat(u uint64, f float64) int {
+	switch {
+	case f >= twoTo64:
+		return -1
+	case f < 0:
+		return 1
+	}
+	t := math.Trunc(f)
+	if tu := uint64(t); u != tu {
+		return cmp3(u < tu, u > tu)
+	}
+	return cmp3(f > t, f < t)
+}
+
+func cmp3(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+	"testing/quick"
+)
+
+// boundaryNumbers are values around the limits of exact float64 integers and
+// of the integer types.
+var boundaryNumbers = []any{
+	0, math.Copysign(0, -1), 1, -1, 0.5, -0.5, 1.5,
+	int64(1<<53 - 1), int64(1 << 53), int64(1<<53 + 1), float64(1 << 53), float64(1<<53 + 2),
+	int64(math.MaxInt64), int64(math.MaxInt64 - 1), int64(math.MinInt64), int64(math.MinInt64 + 1),
+	uint64(math.MaxInt64), uint64(1 << 63), uint64(1<<63 + 1), uint64(math.MaxUint64),
+	float64(1 << 63), float64(1 << 64), -float64(1 << 63), math.Nextafter(1<<63, 0),
+	math.Inf(1), math.Inf(-1), math.MaxFloat64, math.SmallestNonzeroFloat64,
+	json.Number("9007199254740993"), json.Number("18446744073709551615"), json.Number("-9223372036854775809"),
+	"9007199254740993", "1e3", "-0",
+}
+
+// ratOf returns v as an exact rational, or its sign if it is infinite.
+func ratOf(t *testing.T, v any) (*big.Rat, int) {
+	t.Helper()
+	switch x := v.(type) {
+	case int:
+		return new(big.Rat).SetInt64(int64(x)), 0
+	case int64:
+		return new(big.Rat).SetInt64(x), 0
+	case uint64:
+		return new(big.Rat).SetUint64(x), 0
+	case float64:
+		if math.IsInf(x, 0) {
+			return nil, int(math.Copysign(1, x))
+		}
+		return new(big.Rat).SetFloat64(x), 0
+	case json.Number:
+		return ratOf(t, string(x))
+	case string:
+		r, ok := new(big.Rat).SetString(x)
+		if !ok {
+			t.Fatalf("bad test value %q", x)
+		}
+		// Values beyond the integer types are parsed as float64
+		if !r.IsInt() || r.Num().IsInt64() || r.Num().IsUint64() {
+			return r, 0
+		}
+		f, _ := r.Float64()
+		return ratOf(t, f)
+	}
+	t.Fatalf("bad test value %T", v)
+	return nil, 0
+}
+
+func referenceCompare(t *testing.T, a, b any) int {
+	ra, ia := ratOf(t, a)
+	rb, ib := ratOf(t, b)
+	switch {
+	case ia != 0 || ib != 0:
+		return cmp3(ia < ib, ia > ib)
+	}
+	return ra.Cmp(rb)
+}
+
+func TestCompareNumbers_Boundaries(t *testing.T) {
+	for _, a := range boundaryNumbers {
+		for _, b := range boundaryNumbers {
+			na, ok := ParseNumber(a)
+			if !ok {
+				t.Fatalf("ParseNumber(%v) failed", a)
+			}
+			nb, _ := ParseNumber(b)
+
+			got, ok := CompareNumbers(na, nb)
+			if want := referenceCompare(t, a, b); !ok || got != want {
+				t.Errorf("CompareNumbers(%v, %v) = %d, %v, want %d", a, b, got, ok, want)
+			}
+		}
+	}
+}
+
+func TestCompareNumbers_Properties(t *testing.T) {
+	// Integers compare exactly with each other and with floats
+	intFloat := func(i int64, f float64) bool {
+		if math.IsNaN(f) {
+			return true
+		}
+		got, ok := CompareNumbers(IntNumber(i), FloatNumber(f))
+		return ok && got == referenceCompare(t, i, f)
+	}
+	uintFloat := func(u uint64, f float64) bool {
+		if math.IsNaN(f) {
+			return true
+		}
+		got, ok := CompareNumbers(UintNumber(u), FloatNumber(f))
+		return ok && got == referenceCompare(t, u, f)
+	}
+	intUint := func(i int64, u uint64) bool {
+		got, ok := CompareNumbers(IntNumber(i), UintNumber(u))
+		return ok && got == referenceCompare(t, i, u)
+	}
+	// Neighbouring integers above 2^53 are distinct
+	neighbours := func(u uint64) bool {
+		u |= 1 << 53
+		if u == math.MaxUint64 {
+			u--
+		}
+		got, ok := CompareNumbers(UintNumber(u), UintNumber(u+1))
+		return ok && got == -1
+	}
+	// Comparison is antisymmetric
+	antisymmetric := func(i int64, f float64) bool {
+		a, b := IntNumber(i), FloatNumber(f)
+		x, okx := CompareNumbers(a, b)
+		y, oky := CompareNumbers(b, a)
+		return okx == oky && x == -y
+	}
+
+	for name, property := range map[string]any{
+		"int/float":     intFloat,
+		"uint/float":    uintFloat,
+		"int/uint":      intUint,
+		"neighbours":    neighbours,
+		"antisymmetric": antisymmetric,
+	} {
+		if err := quick.Check(property, &quick.Config{MaxCount: 10000}); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestCompareNumbers_NaN(t *testing.T) {
+	nan := FloatNumber(math.NaN())
+	for _, n := range []Number{nan, IntNumber(1), UintNumber(math.MaxUint64), FloatNumber(1.5)} {
+		if _, ok := CompareNumbers(n, nan); ok {
+			t.Errorf("CompareNumbers(%v, NaN) should be unordered", n)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in   any
+		want Number
+		ok   bool
+	}{
+		{in: 42, want: IntNumber(42), ok: true},
+		{in: 42.0, want: IntNumber(42), ok: true},
+		{in: uint64(42), want: IntNumber(42), ok: true},
+		{in: uint64(math.MaxUint64), want: UintNumber(math.MaxUint64), ok: true},
+		{in: json.Number("9007199254740993"), want: IntNumber(9007199254740993), ok: true},
+		{in: "18446744073709551615", want: UintNumber(math.MaxUint64), ok: true},
+		{in: "2.5", want: FloatNumber(2.5), ok: true},
+		{in: "pro", ok: false},
+		{in: true, ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseNumber(tt.in)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("ParseNumber(%#v) = %+v, %v, want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
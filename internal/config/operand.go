@@ -0,0 +1,299 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/0mjs/goff/internal/cidr"
+	"github.com/0mjs/goff/internal/semver"
+)
+
+// OpCode identifies a condition operator. It is resolved from the operator
+// name at compile time so that evaluation can dispatch through a table
+// instead of comparing strings.
+type OpCode uint8
+
+const (
+	OpInvalid OpCode = iota
+	OpEq
+	OpNeq
+	OpGt
+	OpGte
+	OpLt
+	OpLte
+	OpIn
+	OpContains
+	OpMatches
+	OpStartsWith
+	OpEndsWith
+	OpNotIn
+	OpNotContains
+	OpExists
+	OpNotExists
+	OpEqCI
+	OpInCI
+	OpSemverEq
+	OpSemverGt
+	OpSemverGte
+	OpSemverLt
+	OpSemverLte
+	OpSemverInRange
+	OpBefore
+	OpAfter
+	OpIPInCIDR
+	OpAnyOf
+	OpAllOf
+	OpNoneOf
+
+	NumOpCodes // number of opcodes, for sizing dispatch tables
+)
+
+// opCodes maps the supported operator names to their opcodes.
+var opCodes = map[string]OpCode{
+	"eq":       OpEq,
+	"neq":      OpNeq,
+	"gt":       OpGt,
+	"gte":      OpGte,
+	"lt":       OpLt,
+	"lte":      OpLte,
+	"in":       OpIn,
+	"contains": OpContains,
+	"matches":  OpMatches,
+
+	"starts_with":  OpStartsWith,
+	"ends_with":    OpEndsWith,
+	"not_in":       OpNotIn,
+	"not_contains": OpNotContains,
+	"exists":       OpExists,
+	"not_exists":   OpNotExists,
+	"eq_ci":        OpEqCI,
+	"in_ci":        OpInCI,
+
+	"semver_eq":       OpSemverEq,
+	"semver_gt":       OpSemverGt,
+	"semver_gte":      OpSemverGte,
+	"semver_lt":       OpSemverLt,
+	"semver_lte":      OpSemverLte,
+	"semver_in_range": OpSemverInRange,
+
+	"before": OpBefore,
+	"after":  OpAfter,
+
+	"ip_in_cidr": OpIPInCIDR,
+
+	"any_of":  OpAnyOf,
+	"all_of":  OpAllOf,
+	"none_of": OpNoneOf,
+}
+
+// LookupOp returns the opcode of an operator name.
+func LookupOp(name string) (OpCode, bool) {
+	code, ok := opCodes[name]
+	return code, ok
+}
+
+// Operand is the compiled value of an operator comparing against a single
+// value: the value itself, its string form and, if it is numeric, its number.
+type Operand struct {
+	Value any
+	Str   string
+	Num   Number
+	IsNum bool
+}
+
+// NewOperand compiles a scalar condition value.
+func NewOperand(v any) *Operand {
+	num, isNum := ParseNumber(v)
+	return &Operand{Value: v, Str: FormatValue(v), Num: num, IsNum: isNum}
+}
+
+// StringSet is the compiled list of an in, not_in or in_ci condition: the
+// string forms of its values, case-folded for in_ci.
+type StringSet struct {
+	keys map[string]struct{}
+	fold bool
+}
+
+// NewStringSet compiles a list of values; fold makes lookups
+// case-insensitive.
+func NewStringSet(values []any, fold bool) *StringSet {
+	s := &StringSet{keys: make(map[string]struct{}, len(values)), fold: fold}
+	for _, v := range values {
+		key := FormatValue(v)
+		if fold {
+			key = FoldString(key)
+		}
+		s.keys[key] = struct{}{}
+	}
+	return s
+}
+
+// Has reports whether the string form of a value is in the set.
+func (s *StringSet) Has(str string) bool {
+	if s.fold {
+		str = FoldString(str)
+	}
+	_, ok := s.keys[str]
+	return ok
+}
+
+// CompileOperand compiles the value of a condition for its operator: scalars
+// into an Operand, in lists into a StringSet, and versions, times, CIDRs and
+// set operands into their parsed forms. Values that are already compiled are
+// returned unchanged.
+func CompileOperand(op string, value any) (any, error) {
+	switch op {
+	case "eq", "neq", "eq_ci", "gt", "gte", "lt", "lte",
+		"contains", "not_contains", "starts_with", "ends_with":
+		if operand, ok := value.(*Operand); ok {
+			return operand, nil
+		}
+		return NewOperand(value), nil
+	case "in", "not_in", "in_ci":
+		if set, ok := value.(*StringSet); ok {
+			return set, nil
+		}
+		list, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("'%s' operator requires array value", op)
+		}
+		return NewStringSet(list, op == "in_ci"), nil
+	case "semver_eq", "semver_gt", "semver_gte", "semver_lt", "semver_lte":
+		if v, ok := value.(semver.Version); ok {
+			return v, nil
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q operator requires a version string, got %T", op, value)
+		}
+		return semver.Parse(s)
+	case "semver_in_range":
+		if c, ok := value.(semver.Constraint); ok {
+			return c, nil
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q operator requires a constraint string, got %T", op, value)
+		}
+		return semver.ParseConstraint(s)
+	case "before", "after":
+		if t, ok := value.(TimeOperand); ok {
+			return t, nil
+		}
+		return ParseTimeOperand(value)
+	case "ip_in_cidr":
+		if set, ok := value.(*cidr.Set); ok {
+			return set, nil
+		}
+		return ParseCIDRs(value)
+	case "any_of", "all_of", "none_of":
+		if set, ok := value.(*ValueSet); ok {
+			return set, nil
+		}
+		list, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("'%s' operator requires array value", op)
+		}
+		return NewValueSet(list)
+	}
+	return value, nil
+}
+
+// lazyOperand holds the opcode and compiled value of a condition constructed
+// without Compile, resolved the first time it is evaluated.
+type lazyOperand struct {
+	once  sync.Once
+	code  OpCode
+	value any
+	err   error
+}
+
+// Operand returns the condition's opcode and compiled value: Code and Value
+// if Compile set them, otherwise ones resolved once from Op and Value. The
+// opcode is valid even if compiling the value failed.
+func (c *CompiledCondition) Operand() (OpCode, any, error) {
+	if c.Code != OpInvalid {
+		return c.Code, c.Value, nil
+	}
+	c.lazy.once.Do(func() {
+		code, ok := LookupOp(c.Op)
+		if !ok {
+			c.lazy.err = fmt.Errorf("unknown operator: %s", c.Op)
+			return
+		}
+		c.lazy.code = code
+		c.lazy.value, c.lazy.err = CompileOperand(c.Op, c.Value)
+	})
+	return c.lazy.code, c.lazy.value, c.lazy.err
+}
+
+// FormatValue returns the string form of v as fmt's %v verb prints it,
+// without going through fmt for common types.
+func FormatValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case int32:
+		return strconv.FormatInt(int64(x), 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case json.Number:
+		return string(x)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// FoldString maps s to a canonical case-folded form: two strings have the
+// same form exactly when strings.EqualFold reports them equal. Strings that
+// are already canonical are returned without allocating.
+func FoldString(s string) string {
+	for i, r := range s {
+		if foldRune(r) != r || r == utf8.RuneError {
+			var b strings.Builder
+			b.Grow(len(s))
+			b.WriteString(s[:i])
+			for _, r := range s[i:] {
+				b.WriteRune(foldRune(r))
+			}
+			return b.String()
+		}
+	}
+	return s
+}
+
+// foldRune returns a fixed member of r's case-folding orbit: its ASCII
+// lowercase letter if it has one, so lowercase ASCII is left alone, and the
+// smallest rune otherwise.
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if 'a' <= f && f <= 'z' {
+			return f
+		}
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
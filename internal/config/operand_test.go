@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompileOperand(t *testing.T) {
+	tests := []struct {
+		op    string
+		value any
+		check func(t *testing.T, got any)
+	}{
+		{"eq", "42", func(t *testing.T, got any) {
+			o := got.(*Operand)
+			if o.Str != "42" || !o.IsNum || o.Num != IntNumber(42) {
+				t.Errorf("Operand = %+v, want numeric 42", o)
+			}
+		}},
+		{"contains", 3.5, func(t *testing.T, got any) {
+			if o := got.(*Operand); o.Str != "3.5" || !o.IsNum {
+				t.Errorf("Operand = %+v, want 3.5", o)
+			}
+		}},
+		{"gt", "many", func(t *testing.T, got any) {
+			if o := got.(*Operand); o.IsNum {
+				t.Errorf("Operand = %+v, want non-numeric", o)
+			}
+		}},
+		{"in", []any{"us", 1, true}, func(t *testing.T, got any) {
+			set := got.(*StringSet)
+			if !set.Has("us") || !set.Has("1") || !set.Has("true") || set.Has("US") {
+				t.Error("in set has wrong members")
+			}
+		}},
+		{"in_ci", []any{"US", "Straße"}, func(t *testing.T, got any) {
+			set := got.(*StringSet)
+			if !set.Has("us") || !set.Has("STRAßE") || set.Has("uk") {
+				t.Error("in_ci set has wrong members")
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			got, err := CompileOperand(tt.op, tt.value)
+			if err != nil {
+				t.Fatalf("CompileOperand() error = %v", err)
+			}
+			tt.check(t, got)
+
+			// Compiling again is a no-op
+			again, err := CompileOperand(tt.op, got)
+			if err != nil {
+				t.Fatalf("CompileOperand() on compiled value error = %v", err)
+			}
+			if again != got {
+				t.Error("CompileOperand() should return compiled values unchanged")
+			}
+		})
+	}
+
+	if _, err := CompileOperand("in", "us"); err == nil {
+		t.Error("CompileOperand() should reject an in value that is not a list")
+	}
+}
+
+func TestCompile_OpCodes(t *testing.T) {
+	compiled, err := Compile(&Config{
+		Version: 1,
+		Flags: map[string]Flag{
+			"test": {
+				Type: "bool",
+				Rules: []Rule{{
+					When: WhenCondition{All: []AttributeCondition{
+						{Attr: "plan", Op: "eq", Value: "pro"},
+						{Attr: "region", Op: "not_in", Value: []any{"cn"}},
+					}},
+					Then: ThenAction{Variants: map[string]int{"true": 100}},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	conds := compiled.Flags["test"].Rules[0].Conditions
+	if conds[0].Code != OpEq || conds[1].Code != OpNotIn {
+		t.Errorf("Codes = %d, %d, want %d, %d", conds[0].Code, conds[1].Code, OpEq, OpNotIn)
+	}
+	if _, ok := conds[0].Value.(*Operand); !ok {
+		t.Errorf("eq value = %T, want *Operand", conds[0].Value)
+	}
+	if _, ok := conds[1].Value.(*StringSet); !ok {
+		t.Errorf("not_in value = %T, want *StringSet", conds[1].Value)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	for _, v := range []any{"pro", true, 42, int64(-7), int32(3), uint64(1 << 63), 3.14, 1e21, float32(0.1), []any{1, "a"}, nil} {
+		if got, want := FormatValue(v), fmt.Sprintf("%v", v); got != want {
+			t.Errorf("FormatValue(%#v) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func FuzzFoldString(f *testing.F) {
+	f.Add("Pro", "pRO")
+	f.Add("straße", "STRASSE")
+	f.Add("K", "K")
+	f.Add("\xff", "\xfe")
+	f.Fuzz(func(t *testing.T, a, b string) {
+		if got, want := FoldString(a) == FoldString(b), strings.EqualFold(a, b); got != want {
+			t.Errorf("FoldString(%q) == FoldString(%q) is %v, EqualFold is %v", a, b, got, want)
+		}
+	})
+}
@@ -14,6 +14,20 @@ flags:
       true: 50
       false: 50
     default: false`))
+	f.Add([]byte(`version: 1
+flags:
+  test:
+    type: bool
+    variants:
+      true: 0
+      false: 100
+    rules:
+      - expr: seats * price > 10000 && plan in ["pro", "ent"]
+        then:
+          variants:
+            true: 100
+            false: 0
+    default: false`))
 
 	f.Fuzz(func(t *testing.T, data []byte) {
 		// Should not panic
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathSegment is one step of an attribute path: a map key or struct field,
+// or a slice index.
+type PathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// IsPath reports whether attr uses path syntax and needs ParsePath.
+func IsPath(attr string) bool {
+	return strings.ContainsAny(attr, ".[")
+}
+
+// ParsePath splits an attribute path such as "org.plan" or "items[0].sku"
+// into segments. Keys are separated by dots; indexes follow a key in
+// brackets and may be chained, as in "matrix[1][2]".
+func ParsePath(attr string) ([]PathSegment, error) {
+	var segments []PathSegment
+	for _, part := range strings.Split(attr, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" {
+			return nil, fmt.Errorf("invalid attribute path %q: empty key", attr)
+		}
+		segments = append(segments, PathSegment{Key: key})
+
+		if len(part) > len(key) {
+			rest = "[" + rest
+		}
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid attribute path %q: malformed index", attr)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid attribute path %q: index must be a non-negative integer", attr)
+			}
+			segments = append(segments, PathSegment{Index: index, IsIndex: true})
+			rest = rest[end+1:]
+		}
+	}
+	return segments, nil
+}
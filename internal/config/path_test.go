@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		attr    string
+		want    []PathSegment
+		wantErr bool
+	}{
+		{attr: "org.plan", want: []PathSegment{{Key: "org"}, {Key: "plan"}}},
+		{attr: "items[0].sku", want: []PathSegment{{Key: "items"}, {Index: 0, IsIndex: true}, {Key: "sku"}}},
+		{attr: "matrix[1][2]", want: []PathSegment{{Key: "matrix"}, {Index: 1, IsIndex: true}, {Index: 2, IsIndex: true}}},
+		{attr: "org..plan", wantErr: true},
+		{attr: ".plan", wantErr: true},
+		{attr: "[0]", wantErr: true},
+		{attr: "items[x]", wantErr: true},
+		{attr: "items[-1]", wantErr: true},
+		{attr: "items[0", wantErr: true},
+		{attr: "items[0]x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.attr, func(t *testing.T) {
+			got, err := ParsePath(tt.attr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePath() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_AttributePaths(t *testing.T) {
+	yaml := `
+version: 1
+flags:
+  test:
+    enabled: true
+    type: bool
+    default: false
+    rules:
+      - when:
+          all:
+            - attr: org.plan
+              op: eq
+              value: pro
+            - attr: plan
+              op: eq
+              value: pro
+        then:
+          variants:
+            "true": 100
+`
+	cfg, err := LoadFromBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+	compiled, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	conds := compiled.Flags["test"].Rules[0].Conditions
+	if want := []PathSegment{{Key: "org"}, {Key: "plan"}}; !reflect.DeepEqual(conds[0].Path, want) {
+		t.Errorf("Path = %+v, want %+v", conds[0].Path, want)
+	}
+	if conds[1].Path != nil {
+		t.Errorf("plain attribute should have no path, got %+v", conds[1].Path)
+	}
+
+	bad := `
+version: 1
+flags:
+  test:
+    type: bool
+    rules:
+      - when:
+          all:
+            - attr: "org..plan"
+              op: eq
+              value: pro
+        then:
+          variants:
+            "true": 100
+`
+	if _, err := LoadFromBytes([]byte(bad)); err == nil {
+		t.Error("LoadFromBytes() should reject a malformed attribute path")
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Prerequisite requires another flag to evaluate to a given variant for the
+// same context before a flag's own targeting runs.
+type Prerequisite struct {
+	Flag    string `yaml:"flag"`
+	Variant string `yaml:"variant"` // for json flags, the name of a value
+}
+
+// CompiledPrerequisite is a prerequisite resolved to the flag it refers to.
+type CompiledPrerequisite struct {
+	Key     string
+	Flag    *CompiledFlag
+	Variant string
+	Value   any // the variant's typed value; for json flags, the variant name
+}
+
+// checkPrerequisites verifies that prerequisites refer to existing flags and
+// variants and that they do not form a cycle.
+func checkPrerequisites(flags map[string]Flag) error {
+	keys := make([]string, 0, len(flags))
+	for key := range flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for i, pre := range flags[key].Prerequisites {
+			target, ok := flags[pre.Flag]
+			if !ok {
+				return fmt.Errorf("flag %q: prerequisite %d: flag %q does not exist", key, i, pre.Flag)
+			}
+			if pre.Flag == key {
+				return fmt.Errorf("flag %q: prerequisite %d: flag cannot depend on itself", key, i)
+			}
+			if err := target.validateVariantName(pre.Variant); err != nil {
+				return fmt.Errorf("flag %q: prerequisite %d: %w", key, i, err)
+			}
+		}
+	}
+
+	// Depth-first search; a flag reached again while still on the path
+	// closes a cycle
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(flags))
+	var path []string
+	var visit func(key string) error
+	visit = func(key string) error {
+		switch state[key] {
+		case visiting:
+			start := 0
+			for path[start] != key {
+				start++
+			}
+			cycle := append(path[start:], key)
+			return fmt.Errorf("prerequisite cycle: %s", strings.Join(cycle, " -> "))
+		case done:
+			return nil
+		}
+		state[key] = visiting
+		path = append(path, key)
+		for _, pre := range flags[key].Prerequisites {
+			if err := visit(pre.Flag); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[key] = done
+		return nil
+	}
+
+	for _, key := range keys {
+		if err := visit(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkPrerequisites points the prerequisites of each compiled flag at the
+// flags they refer to. checkPrerequisites must have succeeded.
+func linkPrerequisites(cfg *Config, compiled *Compiled) {
+	for key, flag := range cfg.Flags {
+		compiledFlag := compiled.Flags[key]
+		for _, pre := range flag.Prerequisites {
+			target := compiled.Flags[pre.Flag]
+			value := any(pre.Variant)
+			if target.Type != "json" {
+				value, _ = ParseVariant(target.Type, pre.Variant)
+			}
+			compiledFlag.Prerequisites = append(compiledFlag.Prerequisites, CompiledPrerequisite{
+				Key:     pre.Flag,
+				Flag:    target,
+				Variant: pre.Variant,
+				Value:   value,
+			})
+		}
+	}
+}
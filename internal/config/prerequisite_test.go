@@ -0,0 +1,107 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPrerequisites(t *testing.T) {
+	boolFlag := func(prereqs ...Prerequisite) Flag {
+		return Flag{
+			Enabled:       true,
+			Type:          "bool",
+			Variants:      map[string]int{"true": 50, "false": 50},
+			Default:       false,
+			Prerequisites: prereqs,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		flags   map[string]Flag
+		wantErr string
+	}{
+		{
+			name: "valid chain",
+			flags: map[string]Flag{
+				"a": boolFlag(Prerequisite{Flag: "b", Variant: "true"}),
+				"b": boolFlag(Prerequisite{Flag: "c", Variant: "false"}),
+				"c": boolFlag(),
+			},
+		},
+		{
+			name: "missing flag",
+			flags: map[string]Flag{
+				"a": boolFlag(Prerequisite{Flag: "nope", Variant: "true"}),
+			},
+			wantErr: `flag "nope" does not exist`,
+		},
+		{
+			name: "self reference",
+			flags: map[string]Flag{
+				"a": boolFlag(Prerequisite{Flag: "a", Variant: "true"}),
+			},
+			wantErr: "cannot depend on itself",
+		},
+		{
+			name: "invalid variant",
+			flags: map[string]Flag{
+				"a": boolFlag(Prerequisite{Flag: "b", Variant: "yes"}),
+				"b": boolFlag(),
+			},
+			wantErr: "must be 'true' or 'false'",
+		},
+		{
+			name: "cycle",
+			flags: map[string]Flag{
+				"a": boolFlag(Prerequisite{Flag: "b", Variant: "true"}),
+				"b": boolFlag(Prerequisite{Flag: "c", Variant: "true"}),
+				"c": boolFlag(Prerequisite{Flag: "a", Variant: "true"}),
+			},
+			wantErr: "prerequisite cycle: a -> b -> c -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(&Config{Version: 1, Flags: tt.flags})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Compile() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Compile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompile_Prerequisites(t *testing.T) {
+	cfg := &Config{
+		Version: 1,
+		Flags: map[string]Flag{
+			"limit": {
+				Enabled:  true,
+				Type:     "int",
+				Variants: map[string]int{"10": 100},
+			},
+			"upsell": {
+				Enabled:       true,
+				Type:          "bool",
+				Prerequisites: []Prerequisite{{Flag: "limit", Variant: "10"}},
+			},
+		},
+	}
+
+	compiled, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	pre := compiled.Flags["upsell"].Prerequisites
+	if len(pre) != 1 || pre[0].Flag != compiled.Flags["limit"] || pre[0].Value != int64(10) {
+		t.Errorf("Prerequisites = %+v, want a link to limit with value 10", pre)
+	}
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Rollout ramps one variant of a split up over time, either linearly from
+// Start to End or in discrete Steps. The ramped variant grows upwards from
+// where the split already places it, wrapping around the bucket space, so a
+// context that has received it keeps it as the percentage grows, and a ramp
+// starting at the variant's configured share changes no one's variant. The
+// remaining buckets are shared by the split's other variants in proportion
+// to their configured shares, in the same order as in the split.
+type Rollout struct {
+	Variant string        `yaml:"variant"`
+	Start   time.Time     `yaml:"start,omitempty"`
+	End     time.Time     `yaml:"end,omitempty"`
+	From    float64       `yaml:"from,omitempty"`  // percentage at Start
+	To      *float64      `yaml:"to,omitempty"`    // percentage at End; nil means 100
+	Steps   []RolloutStep `yaml:"steps,omitempty"` // alternative to a linear ramp
+}
+
+// RolloutStep sets the rollout percentage from At onwards.
+type RolloutStep struct {
+	At      time.Time `yaml:"at"`
+	Percent float64   `yaml:"percent"`
+}
+
+// CompiledRollout is a rollout with percentages converted to buckets.
+type CompiledRollout struct {
+	Variant string
+	Start   time.Time
+	End     time.Time
+	From    int // buckets at Start
+	To      int // buckets at End
+	Steps   []CompiledRolloutStep
+	Offset  int           // first bucket of the ramp: the variant's lower bound in the split
+	Rest    *Distribution // the split's other variants
+}
+
+// CompiledRolloutStep is a rollout step with its percentage in buckets.
+type CompiledRolloutStep struct {
+	At      time.Time
+	Buckets int
+}
+
+// validateRollout checks a rollout against the split it ramps.
+func (f *Flag) validateRollout(r *Rollout, variants, weights map[string]int) error {
+	split := splitOf(variants, weights)
+	if len(split) == 0 {
+		return fmt.Errorf("rollout requires 'variants' or 'weights'")
+	}
+	if _, ok := split[r.Variant]; !ok {
+		return fmt.Errorf("rollout variant %q is not one of the split's variants", r.Variant)
+	}
+	rest := 0
+	for name, w := range split {
+		if name != r.Variant {
+			rest += w
+		}
+	}
+	if rest == 0 {
+		return fmt.Errorf("rollout needs another variant with a non-zero share")
+	}
+
+	if len(r.Steps) > 0 {
+		if !r.Start.IsZero() || !r.End.IsZero() || r.From != 0 || r.To != nil {
+			return fmt.Errorf("rollout cannot have both 'steps' and a linear ramp")
+		}
+		for i, step := range r.Steps {
+			if step.At.IsZero() {
+				return fmt.Errorf("rollout step %d: missing 'at'", i)
+			}
+			if err := validateRolloutPercent(step.Percent); err != nil {
+				return fmt.Errorf("rollout step %d: %w", i, err)
+			}
+			if i > 0 {
+				prev := r.Steps[i-1]
+				if !step.At.After(prev.At) {
+					return fmt.Errorf("rollout step %d: steps must be in increasing time order", i)
+				}
+				if step.Percent < prev.Percent {
+					return fmt.Errorf("rollout step %d: percentage cannot decrease, got %v after %v", i, step.Percent, prev.Percent)
+				}
+			}
+		}
+		return nil
+	}
+
+	if r.Start.IsZero() || r.End.IsZero() {
+		return fmt.Errorf("rollout requires 'steps' or both 'start' and 'end'")
+	}
+	if !r.End.After(r.Start) {
+		return fmt.Errorf("rollout end must be after start")
+	}
+	if err := validateRolloutPercent(r.From); err != nil {
+		return fmt.Errorf("rollout from: %w", err)
+	}
+	if err := validateRolloutPercent(r.to()); err != nil {
+		return fmt.Errorf("rollout to: %w", err)
+	}
+	if r.to() < r.From {
+		return fmt.Errorf("rollout percentage cannot decrease, got from %v to %v", r.From, r.to())
+	}
+	return nil
+}
+
+func validateRolloutPercent(p float64) error {
+	if p < 0 || p > 100 || math.IsNaN(p) {
+		return fmt.Errorf("percentage must be 0-100, got %v", p)
+	}
+	return nil
+}
+
+func (r *Rollout) to() float64 {
+	if r.To == nil {
+		return 100
+	}
+	return *r.To
+}
+
+// compileRollout converts r for evaluation. Returns nil if r is nil.
+func compileRollout(r *Rollout, variants, weights map[string]int) *CompiledRollout {
+	if r == nil {
+		return nil
+	}
+
+	unit, split := BucketsPerPercent, variants
+	if len(weights) > 0 {
+		unit, split = 1, weights
+	}
+	rest := make(map[string]int, len(split))
+	offset := 0
+	for name, w := range split {
+		if name != r.Variant {
+			rest[name] = w * unit
+		}
+		// Distribution orders variants by name
+		if name < r.Variant {
+			offset += w * unit
+		}
+	}
+
+	compiled := &CompiledRollout{
+		Variant: r.Variant,
+		Start:   r.Start,
+		End:     r.End,
+		From:    percentBuckets(r.From),
+		To:      percentBuckets(r.to()),
+		Offset:  offset % BucketCount,
+		Rest:    NewDistribution(rest, 1),
+	}
+	for _, step := range r.Steps {
+		compiled.Steps = append(compiled.Steps, CompiledRolloutStep{At: step.At, Buckets: percentBuckets(step.Percent)})
+	}
+	return compiled
+}
+
+func percentBuckets(p float64) int {
+	return int(math.Round(p * BucketsPerPercent))
+}
+
+// Buckets returns the number of buckets, starting at Offset, that the
+// rollout variant covers at now.
+func (r *CompiledRollout) Buckets(now time.Time) int {
+	if len(r.Steps) > 0 {
+		buckets := 0
+		for _, step := range r.Steps {
+			if now.Before(step.At) {
+				break
+			}
+			buckets = step.Buckets
+		}
+		return buckets
+	}
+
+	switch {
+	case now.Before(r.Start):
+		return r.From
+	case !now.Before(r.End):
+		return r.To
+	}
+	progress := float64(now.Sub(r.Start)) / float64(r.End.Sub(r.Start))
+	return r.From + int(float64(r.To-r.From)*progress)
+}
+
+// Select returns the variant for bucket when the rollout variant covers the
+// ramp buckets from Offset, wrapping around. The other variants are scaled
+// into the remaining buckets, starting after the ramp with those that follow
+// the variant in the split.
+func (r *CompiledRollout) Select(bucket, ramp int) (string, bool) {
+	pos := bucket - r.Offset
+	if pos < 0 {
+		pos += BucketCount
+	}
+	if pos < ramp {
+		return r.Variant, true
+	}
+	if r.Rest == nil || len(r.Rest.Bounds) == 0 {
+		return "", false
+	}
+	total := r.Rest.Bounds[len(r.Rest.Bounds)-1]
+	scaled := int(int64(pos-ramp)*int64(total)/int64(BucketCount-ramp)) + r.Offset
+	if scaled >= total {
+		scaled -= total
+	}
+	return r.Rest.Select(scaled)
+}
+
+// Covers reports whether variant receives any buckets at ramp.
+func (r *CompiledRollout) Covers(variant string, ramp int) bool {
+	if variant == r.Variant {
+		return ramp > 0
+	}
+	return ramp < BucketCount && r.Rest != nil && r.Rest.Covers(variant)
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFromBytes_Rollout(t *testing.T) {
+	yaml := `
+version: 1
+flags:
+  ramp:
+    enabled: true
+    type: "bool"
+    variants:
+      true: 0
+      false: 100
+    rollout:
+      variant: "true"
+      start: 2024-06-01T00:00:00Z
+      end: 2024-06-11T00:00:00Z
+      from: 1
+    default: false
+`
+	cfg, err := LoadFromBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+
+	r := cfg.Flags["ramp"].Rollout
+	if r == nil {
+		t.Fatal("rollout not decoded")
+	}
+	if want := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC); !r.Start.Equal(want) {
+		t.Errorf("Start = %v, want %v", r.Start, want)
+	}
+	if r.From != 1 || r.to() != 100 {
+		t.Errorf("From, To = %v, %v, want 1, 100", r.From, r.to())
+	}
+
+	// An explicit 0 is kept, not treated as omitted
+	cfg, err = LoadFromBytes([]byte(strings.Replace(yaml, "from: 1", "from: 0\n      to: 0", 1)))
+	if err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+	if r := cfg.Flags["ramp"].Rollout; r.to() != 0 {
+		t.Errorf("To = %v, want 0", r.to())
+	}
+}
+
+func TestCompiledRollout_Buckets(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := 60.0
+	linear := compileRollout(&Rollout{
+		Variant: "true",
+		Start:   start,
+		End:     start.Add(10 * time.Hour),
+		From:    10,
+		To:      &to,
+	}, map[string]int{"true": 0, "false": 100}, nil)
+	steps := compileRollout(&Rollout{
+		Variant: "true",
+		Steps: []RolloutStep{
+			{At: start, Percent: 0.1},
+			{At: start.Add(time.Hour), Percent: 5},
+			{At: start.Add(2 * time.Hour), Percent: 100},
+		},
+	}, map[string]int{"true": 0, "false": 100}, nil)
+
+	tests := []struct {
+		name    string
+		rollout *CompiledRollout
+		at      time.Duration
+		want    int
+	}{
+		{"linear before start", linear, -time.Hour, 10 * BucketsPerPercent},
+		{"linear at start", linear, 0, 10 * BucketsPerPercent},
+		{"linear halfway", linear, 5 * time.Hour, 35 * BucketsPerPercent},
+		{"linear at end", linear, 10 * time.Hour, 60 * BucketsPerPercent},
+		{"linear after end", linear, 20 * time.Hour, 60 * BucketsPerPercent},
+		{"steps before first", steps, -time.Minute, 0},
+		{"steps first", steps, 0, 100},
+		{"steps between", steps, 90 * time.Minute, 5 * BucketsPerPercent},
+		{"steps last", steps, 3 * time.Hour, BucketCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rollout.Buckets(start.Add(tt.at)); got != tt.want {
+				t.Errorf("Buckets() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompiledRollout_Select(t *testing.T) {
+	r := compileRollout(&Rollout{Variant: "new"}, map[string]int{"new": 0, "a": 25, "b": 75}, nil)
+	ramp := 20 * BucketsPerPercent
+
+	counts := make(map[string]int)
+	for bucket := 0; bucket < BucketCount; bucket++ {
+		v, ok := r.Select(bucket, ramp)
+		if !ok {
+			t.Fatalf("Select(%d) failed", bucket)
+		}
+		counts[v]++
+	}
+
+	// The remaining 80% is shared 25:75
+	want := map[string]int{"new": 20000, "a": 20000, "b": 60000}
+	for v, n := range want {
+		if counts[v] != n {
+			t.Errorf("%s covers %d buckets, want %d", v, counts[v], n)
+		}
+	}
+
+	if !r.Covers("new", ramp) || r.Covers("new", 0) {
+		t.Error("rollout variant coverage should follow the ramp")
+	}
+	if r.Covers("a", BucketCount) {
+		t.Error("other variants should not be covered at 100%")
+	}
+}
+
+func TestCompiledRollout_StartMatchesSplit(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		flagType string
+		variants map[string]int
+		weights  map[string]int
+		rollout  Rollout
+	}{
+		{
+			name:     "live bool flag",
+			flagType: "bool",
+			variants: map[string]int{"true": 10, "false": 90},
+			rollout:  Rollout{Variant: "true", Start: start, End: end, From: 10},
+		},
+		{
+			name:     "variant sorted first",
+			flagType: "bool",
+			variants: map[string]int{"true": 70, "false": 30},
+			rollout:  Rollout{Variant: "false", Start: start, End: end, From: 30},
+		},
+		{
+			name:     "middle variant",
+			flagType: "string",
+			variants: map[string]int{"a": 25, "b": 15, "c": 60},
+			rollout:  Rollout{Variant: "b", Start: start, End: end, From: 15},
+		},
+		{
+			name:     "weights",
+			flagType: "string",
+			weights:  map[string]int{"a": 33333, "b": 33333, "c": 33334},
+			rollout:  Rollout{Variant: "b", Steps: []RolloutStep{{At: start, Percent: 33.333}, {At: start.Add(6 * time.Hour), Percent: 50}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag := Flag{Enabled: true, Type: tt.flagType, Variants: tt.variants, Weights: tt.weights, Rollout: &tt.rollout}
+			if err := flag.Validate("test"); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			compiled, err := compileFlag("test", &flag, nil)
+			if err != nil {
+				t.Fatalf("compileFlag() error = %v", err)
+			}
+			r := compiled.Rollout
+
+			// At Start the rollout selects exactly what the static split does
+			ramp := r.Buckets(start)
+			for bucket := 0; bucket < BucketCount; bucket++ {
+				want, _ := compiled.Distribution.Select(bucket)
+				got, ok := r.Select(bucket, ramp)
+				if !ok || got != want {
+					t.Fatalf("bucket %d: rollout selects %q, split selects %q", bucket, got, want)
+				}
+			}
+
+			// Contexts with the variant keep it as the ramp grows
+			later := r.Buckets(start.Add(12 * time.Hour))
+			if later <= ramp {
+				t.Fatalf("ramp did not grow: %d -> %d", ramp, later)
+			}
+			for bucket := 0; bucket < BucketCount; bucket++ {
+				if v, _ := r.Select(bucket, ramp); v == r.Variant {
+					if v, _ := r.Select(bucket, later); v != r.Variant {
+						t.Fatalf("bucket %d left the rollout", bucket)
+					}
+				}
+			}
+		})
+	}
+}
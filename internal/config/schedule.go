@@ -0,0 +1,187 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule limits when a flag or rule is active: between ActiveFrom and
+// ActiveUntil, and, if Windows are given, only during one of them.
+type Schedule struct {
+	ActiveFrom  time.Time `yaml:"active_from,omitempty"`
+	ActiveUntil time.Time `yaml:"active_until,omitempty"` // exclusive
+	Windows     []Window  `yaml:"windows,omitempty"`
+	Timezone    string    `yaml:"timezone,omitempty"` // IANA name the windows are in; defaults to UTC
+}
+
+// Window is a recurring weekly time range. A window whose End is not after
+// its Start crosses midnight and ends on the following day.
+type Window struct {
+	Days  []string `yaml:"days,omitempty"` // "mon".."sun", the days it starts on; empty means every day
+	Start string   `yaml:"start"`          // "HH:MM"
+	End   string   `yaml:"end"`            // "HH:MM", exclusive; "24:00" for end of day
+}
+
+// CompiledSchedule is a schedule ready for evaluation.
+type CompiledSchedule struct {
+	From     time.Time
+	Until    time.Time
+	Location *time.Location
+	Windows  []CompiledWindow
+}
+
+// CompiledWindow is a window with its days as a bitmask of 1<<time.Weekday
+// and its times as minutes since midnight. A window crossing midnight is
+// compiled into two, the second on the following days.
+type CompiledWindow struct {
+	Days  uint8
+	Start int
+	End   int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+const (
+	everyDay      = 1<<7 - 1
+	minutesPerDay = 24 * 60
+)
+
+// IsZero reports whether the schedule places no restriction.
+func (s *Schedule) IsZero() bool {
+	return s.ActiveFrom.IsZero() && s.ActiveUntil.IsZero() && len(s.Windows) == 0 && s.Timezone == ""
+}
+
+// compileSchedule validates and compiles s. Returns nil if s places no
+// restriction.
+func compileSchedule(s *Schedule) (*CompiledSchedule, error) {
+	if s.IsZero() {
+		return nil, nil
+	}
+
+	if !s.ActiveFrom.IsZero() && !s.ActiveUntil.IsZero() && !s.ActiveUntil.After(s.ActiveFrom) {
+		return nil, fmt.Errorf("active_until must be after active_from")
+	}
+
+	loc := time.UTC
+	if s.Timezone != "" {
+		if len(s.Windows) == 0 {
+			return nil, fmt.Errorf("timezone requires 'windows'")
+		}
+		var err error
+		loc, err = time.LoadLocation(s.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+		}
+	}
+
+	compiled := &CompiledSchedule{
+		From:     s.ActiveFrom,
+		Until:    s.ActiveUntil,
+		Location: loc,
+		Windows:  make([]CompiledWindow, 0, len(s.Windows)),
+	}
+	index := make([]int, 0, len(s.Windows)) // window each compiled window came from
+	for i, w := range s.Windows {
+		parts, err := compileWindow(&w)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i, err)
+		}
+		for _, cw := range parts {
+			for j, prev := range compiled.Windows {
+				if cw.Days&prev.Days != 0 && cw.Start < prev.End && prev.Start < cw.End {
+					return nil, fmt.Errorf("window %d overlaps window %d", i, index[j])
+				}
+			}
+			compiled.Windows = append(compiled.Windows, cw)
+			index = append(index, i)
+		}
+	}
+	return compiled, nil
+}
+
+// compileWindow compiles w into one window, or two if it crosses midnight.
+func compileWindow(w *Window) ([]CompiledWindow, error) {
+	var cw CompiledWindow
+	for _, day := range w.Days {
+		wd, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+		cw.Days |= 1 << wd
+	}
+	if len(w.Days) == 0 {
+		cw.Days = everyDay
+	}
+
+	var err error
+	if cw.Start, err = parseClock(w.Start); err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	if cw.End, err = parseClock(w.End); err != nil {
+		return nil, fmt.Errorf("end: %w", err)
+	}
+	switch {
+	case cw.Start == minutesPerDay:
+		return nil, fmt.Errorf("start cannot be 24:00")
+	case cw.End == cw.Start:
+		return nil, fmt.Errorf("end %s must differ from start %s", w.End, w.Start)
+	case cw.End > cw.Start:
+		return []CompiledWindow{cw}, nil
+	}
+
+	// Crosses midnight: the rest of the start day, then the next morning
+	parts := []CompiledWindow{{Days: cw.Days, Start: cw.Start, End: minutesPerDay}}
+	if cw.End > 0 {
+		next := (cw.Days<<1 | cw.Days>>6) & everyDay
+		parts = append(parts, CompiledWindow{Days: next, Start: 0, End: cw.End})
+	}
+	return parts, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight. "24:00" is accepted
+// as the end of the day.
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok || len(hh) != 2 || len(mm) != 2 {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return h*60 + m, nil
+}
+
+// Active reports whether now falls within the schedule.
+func (s *CompiledSchedule) Active(now time.Time) bool {
+	if !s.From.IsZero() && now.Before(s.From) {
+		return false
+	}
+	if !s.Until.IsZero() && !now.Before(s.Until) {
+		return false
+	}
+	if len(s.Windows) == 0 {
+		return true
+	}
+
+	local := now.In(s.Location)
+	day := uint8(1) << local.Weekday()
+	minute := local.Hour()*60 + local.Minute()
+	for _, w := range s.Windows {
+		if w.Days&day != 0 && minute >= w.Start && minute < w.End {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompileSchedule(t *testing.T) {
+	from := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			schedule: Schedule{},
+		},
+		{
+			name:     "valid range",
+			schedule: Schedule{ActiveFrom: from, ActiveUntil: from.Add(72 * time.Hour)},
+		},
+		{
+			name:     "inverted range",
+			schedule: Schedule{ActiveFrom: from, ActiveUntil: from.Add(-time.Hour)},
+			wantErr:  true,
+		},
+		{
+			name: "valid windows",
+			schedule: Schedule{
+				Timezone: "America/New_York",
+				Windows: []Window{
+					{Days: []string{"mon", "tue"}, Start: "09:00", End: "12:00"},
+					{Days: []string{"mon"}, Start: "12:00", End: "24:00"},
+					{Days: []string{"Saturday"}, Start: "09:00", End: "17:00"},
+				},
+			},
+		},
+		{
+			name: "overnight window",
+			schedule: Schedule{
+				Windows: []Window{{Days: []string{"sat"}, Start: "22:00", End: "02:00"}},
+			},
+		},
+		{
+			name: "empty window",
+			schedule: Schedule{
+				Windows: []Window{{Start: "09:00", End: "09:00"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "overnight window overlaps next day",
+			schedule: Schedule{
+				Windows: []Window{
+					{Days: []string{"sat"}, Start: "22:00", End: "02:00"},
+					{Days: []string{"sun"}, Start: "01:00", End: "03:00"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "overlapping windows",
+			schedule: Schedule{
+				Windows: []Window{
+					{Days: []string{"mon", "tue"}, Start: "09:00", End: "12:00"},
+					{Days: []string{"tue"}, Start: "11:00", End: "13:00"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid day",
+			schedule: Schedule{
+				Windows: []Window{{Days: []string{"someday"}, Start: "09:00", End: "12:00"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid time",
+			schedule: Schedule{
+				Windows: []Window{{Start: "9am", End: "12:00"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid timezone",
+			schedule: Schedule{
+				Timezone: "Mars/Olympus_Mons",
+				Windows:  []Window{{Start: "09:00", End: "12:00"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileSchedule(&tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compileSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompiledSchedule_Active(t *testing.T) {
+	from := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	rangeOnly, err := compileSchedule(&Schedule{ActiveFrom: from, ActiveUntil: from.Add(72 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekdays, err := compileSchedule(&Schedule{
+		Timezone: "America/New_York",
+		Windows:  []Window{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overnight, err := compileSchedule(&Schedule{
+		Windows: []Window{{Days: []string{"fri", "sat"}, Start: "22:00", End: "02:00"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule *CompiledSchedule
+		now      time.Time
+		want     bool
+	}{
+		{"before range", rangeOnly, from.Add(-time.Second), false},
+		{"range start", rangeOnly, from, true},
+		{"range end is exclusive", rangeOnly, from.Add(72 * time.Hour), false},
+		// Monday 2024-06-03 10:00 in New York is 14:00 UTC
+		{"weekday window", weekdays, time.Date(2024, 6, 3, 14, 0, 0, 0, time.UTC), true},
+		{"weekday before window", weekdays, time.Date(2024, 6, 3, 12, 59, 0, 0, time.UTC), false},
+		{"window end is exclusive", weekdays, time.Date(2024, 6, 3, 21, 0, 0, 0, time.UTC), false},
+		{"weekend", weekdays, time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC), false},
+		// 2024-06-07 is a Friday
+		{"overnight before start", overnight, time.Date(2024, 6, 7, 21, 59, 0, 0, time.UTC), false},
+		{"overnight start day", overnight, time.Date(2024, 6, 7, 23, 0, 0, 0, time.UTC), true},
+		{"overnight next morning", overnight, time.Date(2024, 6, 8, 1, 59, 0, 0, time.UTC), true},
+		{"overnight end is exclusive", overnight, time.Date(2024, 6, 8, 2, 0, 0, 0, time.UTC), false},
+		{"overnight wraps to sunday", overnight, time.Date(2024, 6, 9, 1, 0, 0, 0, time.UTC), true},
+		{"overnight not after thursday", overnight, time.Date(2024, 6, 7, 1, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Active(tt.now); got != tt.want {
+				t.Errorf("Active(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFromBytes_Schedule(t *testing.T) {
+	yaml := `
+version: 1
+flags:
+  banner:
+    enabled: true
+    type: "bool"
+    active_from: 2024-11-29T00:00:00Z
+    active_until: 2024-12-03T00:00:00Z
+    rules:
+      - when:
+          all:
+            - attr: "plan"
+              op: "eq"
+              value: "pro"
+        then:
+          variants:
+            true: 100
+            false: 0
+        timezone: "Europe/London"
+        windows:
+          - days: ["sat", "sun"]
+            start: "09:00"
+            end: "17:00"
+    default: true
+`
+	cfg, err := LoadFromBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+
+	flag := cfg.Flags["banner"]
+	if flag.ActiveFrom.IsZero() || flag.ActiveUntil.IsZero() {
+		t.Error("flag activation range not decoded")
+	}
+	rule := flag.Rules[0]
+	if rule.Timezone != "Europe/London" || len(rule.Windows) != 1 || len(rule.Windows[0].Days) != 2 {
+		t.Errorf("rule schedule = %+v", rule.Schedule)
+	}
+}
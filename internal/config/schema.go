@@ -0,0 +1,267 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateSchema checks a normalized JSON value against a JSON Schema.
+//
+// Only the commonly used subset of the specification is supported: type,
+// enum, const, properties, required, additionalProperties, items, minItems,
+// maxItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum and exclusiveMaximum. Other keywords are ignored.
+func ValidateSchema(schema map[string]any, value any) error {
+	return validateSchema(schema, value, "$")
+}
+
+func validateSchema(schema map[string]any, value any, path string) error {
+	if t, ok := schema["type"]; ok {
+		if err := checkSchemaType(t, value, path); err != nil {
+			return err
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		values, ok := enum.([]any)
+		if !ok {
+			return fmt.Errorf("%s: schema 'enum' must be an array", path)
+		}
+		found := false
+		for _, v := range values {
+			if jsonEqual(v, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value %v is not one of %v", path, value, values)
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		return fmt.Errorf("%s: value %v must equal %v", path, value, c)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return validateObject(schema, v, path)
+	case []any:
+		return validateArray(schema, v, path)
+	case string:
+		return validateString(schema, v, path)
+	default:
+		if n, ok := jsonNumber(value); ok {
+			return validateNumber(schema, n, path)
+		}
+	}
+	return nil
+}
+
+func validateObject(schema map[string]any, obj map[string]any, path string) error {
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+
+	// Sort keys so the first reported error is deterministic
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPath := path + "." + k
+		if prop, ok := properties[k]; ok {
+			propSchema, ok := prop.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: schema for property must be an object", childPath)
+			}
+			if err := validateSchema(propSchema, obj[k], childPath); err != nil {
+				return err
+			}
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				return fmt.Errorf("%s: additional property not allowed", childPath)
+			}
+		case map[string]any:
+			if err := validateSchema(extra, obj[k], childPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateArray(schema map[string]any, arr []any, path string) error {
+	if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(arr)) < n {
+		return fmt.Errorf("%s: array must have at least %v items, got %d", path, n, len(arr))
+	}
+	if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(arr)) > n {
+		return fmt.Errorf("%s: array must have at most %v items, got %d", path, n, len(arr))
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		for i, elem := range arr {
+			if err := validateSchema(items, elem, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateString(schema map[string]any, s string, path string) error {
+	length := float64(utf8.RuneCountInString(s))
+	if n, ok := schemaNumber(schema, "minLength"); ok && length < n {
+		return fmt.Errorf("%s: string must be at least %v characters", path, n)
+	}
+	if n, ok := schemaNumber(schema, "maxLength"); ok && length > n {
+		return fmt.Errorf("%s: string must be at most %v characters", path, n)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid schema pattern %q: %w", path, pattern, err)
+		}
+		if !re.MatchString(s) {
+			return fmt.Errorf("%s: string %q does not match pattern %q", path, s, pattern)
+		}
+	}
+	return nil
+}
+
+func validateNumber(schema map[string]any, n float64, path string) error {
+	if min, ok := schemaNumber(schema, "minimum"); ok && n < min {
+		return fmt.Errorf("%s: %v is less than minimum %v", path, n, min)
+	}
+	if max, ok := schemaNumber(schema, "maximum"); ok && n > max {
+		return fmt.Errorf("%s: %v is greater than maximum %v", path, n, max)
+	}
+	if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && n <= min {
+		return fmt.Errorf("%s: %v must be greater than %v", path, n, min)
+	}
+	if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && n >= max {
+		return fmt.Errorf("%s: %v must be less than %v", path, n, max)
+	}
+	return nil
+}
+
+// checkSchemaType checks the "type" keyword, which may be a single type name
+// or a list of names.
+func checkSchemaType(t any, value any, path string) error {
+	var names []string
+	switch tv := t.(type) {
+	case string:
+		names = []string{tv}
+	case []any:
+		for _, n := range tv {
+			s, ok := n.(string)
+			if !ok {
+				return fmt.Errorf("%s: schema 'type' must be a string or array of strings", path)
+			}
+			names = append(names, s)
+		}
+	default:
+		return fmt.Errorf("%s: schema 'type' must be a string or array of strings", path)
+	}
+
+	for _, name := range names {
+		if jsonTypeMatches(name, value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(names, " or "), jsonTypeName(value))
+}
+
+func jsonTypeMatches(name string, value any) bool {
+	switch name {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "number":
+		_, ok := jsonNumber(value)
+		return ok
+	case "integer":
+		n, ok := jsonNumber(value)
+		return ok && n == math.Trunc(n)
+	}
+	return false
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	if _, ok := jsonNumber(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func jsonNumber(value any) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func schemaNumber(schema map[string]any, keyword string) (float64, bool) {
+	v, ok := schema[keyword]
+	if !ok {
+		return 0, false
+	}
+	return jsonNumber(v)
+}
+
+// jsonEqual compares two normalized JSON values, treating numbers of
+// different Go types as equal when their values are.
+func jsonEqual(a, b any) bool {
+	an, aok := jsonNumber(a)
+	bn, bok := jsonNumber(b)
+	if aok || bok {
+		return aok && bok && an == bn
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schema := map[string]any{
+		"type":     "object",
+		"required": []any{"name"},
+		"properties": map[string]any{
+			"name":    map[string]any{"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+			"retries": map[string]any{"type": "integer", "minimum": 0, "maximum": 10},
+			"mode":    map[string]any{"enum": []any{"fast", "safe"}},
+			"tags":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "maxItems": 2},
+		},
+		"additionalProperties": false,
+	}
+
+	tests := []struct {
+		name    string
+		value   any
+		wantErr bool
+	}{
+		{"valid", map[string]any{"name": "abc", "retries": 3, "mode": "fast"}, false},
+		{"float integer", map[string]any{"name": "abc", "retries": 3.0}, false},
+		{"not an object", "abc", true},
+		{"missing required", map[string]any{"retries": 3}, true},
+		{"wrong property type", map[string]any{"name": 5}, true},
+		{"pattern mismatch", map[string]any{"name": "ABC"}, true},
+		{"empty string", map[string]any{"name": ""}, true},
+		{"non-integer", map[string]any{"name": "abc", "retries": 1.5}, true},
+		{"above maximum", map[string]any{"name": "abc", "retries": 11}, true},
+		{"not in enum", map[string]any{"name": "abc", "mode": "slow"}, true},
+		{"array items", map[string]any{"name": "abc", "tags": []any{"a", 1}}, true},
+		{"too many items", map[string]any{"name": "abc", "tags": []any{"a", "b", "c"}}, true},
+		{"additional property", map[string]any{"name": "abc", "extra": true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchema(schema, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSchema_TypeList(t *testing.T) {
+	schema := map[string]any{"type": []any{"string", "null"}}
+
+	if err := ValidateSchema(schema, nil); err != nil {
+		t.Errorf("ValidateSchema(nil) error = %v", err)
+	}
+	if err := ValidateSchema(schema, "x"); err != nil {
+		t.Errorf("ValidateSchema(\"x\") error = %v", err)
+	}
+	if err := ValidateSchema(schema, 1); err == nil {
+		t.Error("ValidateSchema(1) expected error")
+	}
+}
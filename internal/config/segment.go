@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Segment is a named audience that rules can refer to instead of repeating
+// the same conditions. A context belongs to a segment if its key is
+// included, or if it is not excluded and matches the conditions.
+type Segment struct {
+	When     WhenCondition `yaml:"when,omitempty"`
+	Included []string      `yaml:"included,omitempty"` // context keys that always belong
+	Excluded []string      `yaml:"excluded,omitempty"` // context keys that never belong
+}
+
+// CompiledSegment is a segment ready for evaluation.
+type CompiledSegment struct {
+	Name     string
+	When     *CompiledNode // nil if only included keys belong
+	Included map[string]struct{}
+	Excluded map[string]struct{}
+}
+
+// Validate checks a segment for errors.
+func (s *Segment) Validate() error {
+	if s.When.IsZero() && len(s.Included) == 0 {
+		return fmt.Errorf("segment must have 'all' or 'any' condition, or 'included' keys")
+	}
+
+	if err := s.When.Validate(); err != nil {
+		return err
+	}
+
+	included := make(map[string]bool, len(s.Included))
+	for _, key := range s.Included {
+		included[key] = true
+	}
+	for _, key := range s.Excluded {
+		if included[key] {
+			return fmt.Errorf("key %q is both included and excluded", key)
+		}
+	}
+	return nil
+}
+
+func compileSegment(name string, segment *Segment) (*CompiledSegment, error) {
+	when, err := compileWhen(&segment.When)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &CompiledSegment{
+		Name:     name,
+		When:     when,
+		Included: make(map[string]struct{}, len(segment.Included)),
+		Excluded: make(map[string]struct{}, len(segment.Excluded)),
+	}
+	for _, key := range segment.Included {
+		compiled.Included[key] = struct{}{}
+	}
+	for _, key := range segment.Excluded {
+		compiled.Excluded[key] = struct{}{}
+	}
+	return compiled, nil
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSegmentValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		segment Segment
+		wantErr bool
+	}{
+		{
+			name: "conditions",
+			segment: Segment{
+				When: WhenCondition{Any: []AttributeCondition{{Attr: "email", Op: "matches", Value: "@ourco\\.com$"}}},
+			},
+		},
+		{
+			name:    "included keys only",
+			segment: Segment{Included: []string{"user:1"}},
+		},
+		{
+			name:    "empty",
+			segment: Segment{Excluded: []string{"user:1"}},
+			wantErr: true,
+		},
+		{
+			name: "invalid regex",
+			segment: Segment{
+				When: WhenCondition{All: []AttributeCondition{{Attr: "email", Op: "matches", Value: "("}}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "included and excluded",
+			segment: Segment{Included: []string{"user:1"}, Excluded: []string{"user:1"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.segment.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Segment.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+const segmentYAML = `
+version: 1
+segments:
+  internal_staff:
+    when:
+      any:
+        - attr: "email"
+          op: "matches"
+          value: "@ourco\\.com$"
+    included: ["user:contractor"]
+flags:
+  new_checkout:
+    enabled: true
+    type: "bool"
+    rules:
+      - segment: internal_staff
+        then:
+          variants:
+            true: 100
+            false: 0
+    default: false
+  new_search:
+    enabled: true
+    type: "bool"
+    rules:
+      - segment: %s
+        when:
+          all:
+            - attr: "region"
+              op: "eq"
+              value: "eu"
+        then:
+          variants:
+            true: 100
+            false: 0
+    default: false
+`
+
+func TestCompile_SharedSegments(t *testing.T) {
+	cfg, err := LoadFromBytes([]byte(strings.Replace(segmentYAML, "%s", "internal_staff", 1)))
+	if err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+
+	compiled, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	segment := compiled.Segments["internal_staff"]
+	if segment == nil || segment.When == nil || len(segment.When.Any) != 1 || segment.When.Any[0].Condition.Regex == nil {
+		t.Fatalf("segment not compiled: %+v", segment)
+	}
+	for _, key := range []string{"new_checkout", "new_search"} {
+		if got := compiled.Flags[key].Rules[0].Segment; got != segment {
+			t.Errorf("%s: rule segment = %p, want shared %p", key, got, segment)
+		}
+	}
+}
+
+func TestLoadFromBytes_UnknownSegment(t *testing.T) {
+	_, err := LoadFromBytes([]byte(strings.Replace(segmentYAML, "%s", "staff", 1)))
+	if err == nil || !strings.Contains(err.Error(), `unknown segment "staff"`) {
+		t.Errorf("LoadFromBytes() error = %v, want unknown segment", err)
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeOperand is the compiled value of a before or after condition: a fixed
+// instant, or an offset from the time of evaluation.
+type TimeOperand struct {
+	Time     time.Time
+	Relative bool
+	Offset   time.Duration
+}
+
+// At returns the instant the operand refers to when evaluated at now.
+func (t TimeOperand) At(now time.Time) time.Time {
+	if t.Relative {
+		return now.Add(t.Offset)
+	}
+	return t.Time
+}
+
+var relativeUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// ParseTimeOperand parses an instant accepted by ParseInstant, or a relative
+// expression: "now", or "now" followed by a signed offset such as "now-30d"
+// or "now+12h". Units are s, m, h, d and w.
+func ParseTimeOperand(value any) (TimeOperand, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "now") {
+		t, ok := ParseInstant(value)
+		if !ok {
+			return TimeOperand{}, fmt.Errorf("invalid time %v: want RFC3339, YYYY-MM-DD, unix time or now±N[smhdw]", value)
+		}
+		return TimeOperand{Time: t}, nil
+	}
+
+	expr := s[len("now"):]
+	if expr == "" {
+		return TimeOperand{Relative: true}, nil
+	}
+	if len(expr) < 3 || (expr[0] != '+' && expr[0] != '-') {
+		return TimeOperand{}, fmt.Errorf("invalid relative time %q: want now±N[smhdw]", s)
+	}
+	unit, ok := relativeUnits[expr[len(expr)-1]]
+	n, err := strconv.ParseInt(expr[1:len(expr)-1], 10, 64)
+	if !ok || err != nil || n < 0 || n > int64(math.MaxInt64/unit) {
+		return TimeOperand{}, fmt.Errorf("invalid relative time %q: want now±N[smhdw]", s)
+	}
+	offset := time.Duration(n) * unit
+	if expr[0] == '-' {
+		offset = -offset
+	}
+	return TimeOperand{Relative: true, Offset: offset}, nil
+}
+
+// unixMillisThreshold separates unix seconds from unix milliseconds: as
+// seconds it is in the year 5138, as milliseconds in 1973.
+const unixMillisThreshold = 1e11
+
+// ParseInstant converts a time.Time, an RFC3339 or YYYY-MM-DD string (UTC),
+// or a number of unix seconds or milliseconds into a time.
+func ParseInstant(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, false
+		}
+		return *t, true
+	case string:
+		if parsed, err := time.Parse(time.RFC3339, t); err == nil {
+			return parsed, true
+		}
+		if parsed, err := time.Parse(time.DateOnly, t); err == nil {
+			return parsed, true
+		}
+		return time.Time{}, false
+	case int:
+		return unixTime(int64(t)), true
+	case int64:
+		return unixTime(t), true
+	case uint64:
+		if t > math.MaxInt64 {
+			return time.Time{}, false
+		}
+		return unixTime(int64(t)), true
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) || math.Abs(t) >= math.MaxInt64/1e6 {
+			return time.Time{}, false
+		}
+		if math.Abs(t) >= unixMillisThreshold {
+			return time.UnixMilli(int64(t)).UTC(), true
+		}
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+func unixTime(n int64) time.Time {
+	if n >= unixMillisThreshold || n <= -unixMillisThreshold {
+		return time.UnixMilli(n).UTC()
+	}
+	return time.Unix(n, 0).UTC()
+}
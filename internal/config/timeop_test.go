@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeOperand(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   any
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "rfc3339", value: "2026-01-01T09:30:00+01:00", want: time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{name: "date", value: "2026-01-01", want: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "yaml timestamp", value: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), want: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "unix seconds", value: 1767225600, want: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "unix millis", value: int64(1767225600123), want: time.Date(2026, 1, 1, 0, 0, 0, 123e6, time.UTC)},
+		{name: "now", value: "now", want: now},
+		{name: "now minus days", value: "now-30d", want: now.AddDate(0, 0, -30)},
+		{name: "now plus hours", value: "now+12h", want: now.Add(12 * time.Hour)},
+		{name: "now plus weeks", value: "now+1w", want: now.AddDate(0, 0, 7)},
+		{name: "missing sign", value: "now30d", wantErr: true},
+		{name: "missing number", value: "now-d", wantErr: true},
+		{name: "unknown unit", value: "now-3y", wantErr: true},
+		{name: "overflow", value: "now+99999999999w", wantErr: true},
+		{name: "garbage", value: "yesterday", wantErr: true},
+		{name: "bool", value: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimeOperand(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeOperand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.At(now).Equal(tt.want) {
+				t.Errorf("ParseTimeOperand().At() = %v, want %v", got.At(now), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// NormalizeValue converts a decoded YAML/JSON value to the canonical Go type
+// for the given flag type: bool, string, int64 or float64. For json flags the
+// value is the name of a variant, so a string.
+// Returns false if the value cannot be represented by the flag type.
+func NormalizeValue(flagType string, v any) (any, bool) {
+	switch flagType {
+	case "bool":
+		b, ok := v.(bool)
+		return b, ok
+	case "string", "json":
+		// json flag defaults name one of the flag's values
+		s, ok := v.(string)
+		return s, ok
+	case "int":
+		switch n := v.(type) {
+		case int:
+			return int64(n), true
+		case int64:
+			return n, true
+		case int32:
+			return int64(n), true
+		case uint64:
+			if n > math.MaxInt64 {
+				return nil, false
+			}
+			return int64(n), true
+		case float64:
+			// JSON decoders produce float64 for every number
+			if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+				return nil, false
+			}
+			return int64(n), true
+		}
+		return nil, false
+	case "float":
+		switch n := v.(type) {
+		case float64:
+			return n, true
+		case float32:
+			return float64(n), true
+		case int:
+			return float64(n), true
+		case int64:
+			return float64(n), true
+		case uint64:
+			return float64(n), true
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// ParseVariant converts a variant name to a value of the flag type.
+func ParseVariant(flagType, name string) (any, bool) {
+	switch flagType {
+	case "bool":
+		switch name {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+		return nil, false
+	case "string":
+		return name, true
+	case "int":
+		n, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		return n, true
+	case "float":
+		f, err := strconv.ParseFloat(name, 64)
+		if err != nil {
+			return nil, false
+		}
+		return f, true
+	}
+	return nil, false
+}
+
+// CloneJSON returns a deep copy of a value produced by NormalizeJSON, so that
+// callers can modify it without affecting the compiled flag.
+func CloneJSON(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, elem := range t {
+			out[k] = CloneJSON(elem)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, elem := range t {
+			out[i] = CloneJSON(elem)
+		}
+		return out
+	}
+	return v
+}
+
+// NormalizeJSON converts a decoded YAML value into a form that encoding/json
+// can marshal: mappings become map[string]any and sequences []any.
+// Returns an error for mappings with non-string keys or unsupported types.
+func NormalizeJSON(v any) (any, error) {
+	switch t := v.(type) {
+	case nil, bool, string, int, int64, uint64, float64:
+		return t, nil
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, elem := range t {
+			n, err := NormalizeJSON(elem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = n
+		}
+		return out, nil
+	case map[any]any:
+		out := make(map[string]any, len(t))
+		for k, elem := range t {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("object keys must be strings, got %T", k)
+			}
+			n, err := NormalizeJSON(elem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			out[key] = n
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(t))
+		for i, elem := range t {
+			n, err := NormalizeJSON(elem)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = n
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
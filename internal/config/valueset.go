@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"math"
+)
+
+// ValueSet is a precompiled list of scalar condition values. Membership is
+// by type: strings match strings, numbers match numbers of any Go type with
+// the same value, and bools match bools.
+type ValueSet struct {
+	keys map[setKey]struct{}
+}
+
+type setKind uint8
+
+const (
+	kindString setKind = iota + 1
+	kindNumber
+	kindBool
+)
+
+type setKey struct {
+	kind setKind
+	str  string
+	num  Number
+}
+
+// NewValueSet builds a set from a list of strings, numbers and bools.
+func NewValueSet(values []any) (*ValueSet, error) {
+	s := &ValueSet{keys: make(map[setKey]struct{}, len(values))}
+	for _, v := range values {
+		key, ok := keyOf(v)
+		if !ok {
+			return nil, fmt.Errorf("set values must be strings, numbers or bools, got %T", v)
+		}
+		s.keys[key] = struct{}{}
+	}
+	return s, nil
+}
+
+// Has reports whether v is in the set.
+func (s *ValueSet) Has(v any) bool {
+	key, ok := keyOf(v)
+	if !ok {
+		return false
+	}
+	_, ok = s.keys[key]
+	return ok
+}
+
+// Len returns the number of distinct values in the set.
+func (s *ValueSet) Len() int {
+	return len(s.keys)
+}
+
+// SameValue reports whether a and b are equal under ValueSet membership
+// rules.
+func SameValue(a, b any) bool {
+	ka, ok := keyOf(a)
+	if !ok {
+		return false
+	}
+	kb, ok := keyOf(b)
+	return ok && ka == kb
+}
+
+func keyOf(v any) (setKey, bool) {
+	switch x := v.(type) {
+	case string:
+		return setKey{kind: kindString, str: x}, true
+	case bool:
+		if x {
+			return setKey{kind: kindBool, num: IntNumber(1)}, true
+		}
+		return setKey{kind: kindBool}, true
+	case float64:
+		if math.IsNaN(x) {
+			return setKey{}, false
+		}
+	case float32:
+		if math.IsNaN(float64(x)) {
+			return setKey{}, false
+		}
+	}
+	// Numbers are kept exact, so large IDs do not collide
+	num, ok := ParseNumber(v)
+	if !ok {
+		return setKey{}, false
+	}
+	return setKey{kind: kindNumber, num: num}, true
+}
@@ -0,0 +1,42 @@
+package config
+
+import "testing"
+
+func TestValueSet(t *testing.T) {
+	set, err := NewValueSet([]any{"admin", 1, 2.5, true, "admin"})
+	if err != nil {
+		t.Fatalf("NewValueSet() error = %v", err)
+	}
+	if set.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", set.Len())
+	}
+
+	tests := []struct {
+		value any
+		want  bool
+	}{
+		{"admin", true},
+		{"Admin", false},
+		{1, true},
+		{int64(1), true},
+		{1.0, true},
+		{"1", false},
+		{2.5, true},
+		{float32(2.5), true},
+		{true, true},
+		{false, false},
+		{nil, false},
+		{[]any{"admin"}, false},
+	}
+	for _, tt := range tests {
+		if got := set.Has(tt.value); got != tt.want {
+			t.Errorf("Has(%#v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNewValueSet_Invalid(t *testing.T) {
+	if _, err := NewValueSet([]any{"admin", map[string]any{"role": "admin"}}); err == nil {
+		t.Error("NewValueSet() should reject non-scalar values")
+	}
+}
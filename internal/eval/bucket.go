@@ -2,17 +2,37 @@ package eval
 
 import (
 	"github.com/cespare/xxhash/v2"
+
+	"github.com/0mjs/goff/internal/config"
 )
 
 // HashFlagContext computes a deterministic hash for flag evaluation.
 // Input: flagKey + '\x1f' + contextKey + salt
 // Returns a value in [0..99] for percentage rollouts.
 func HashFlagContext(flagKey, contextKey string, salt uint64) int {
-	h := xxhash.New()
+	// Map to [0..99] for percentage rollouts
+	return int(hashFlagContext(flagKey, contextKey, salt) % 100)
+}
+
+// HashBucket computes the bucket in [0..config.BucketCount) used for
+// rollouts. The bucket is derived from the same hash as HashFlagContext:
+// bucket / config.BucketsPerPercent == HashFlagContext, so whole-percentage
+// splits select exactly the same contexts at either resolution.
+func HashBucket(flagKey, contextKey string, salt uint64) int {
+	hash := hashFlagContext(flagKey, contextKey, salt)
+	percent := hash % 100
+	fine := (hash / 100) % config.BucketsPerPercent
+	return int(percent*config.BucketsPerPercent + fine)
+}
+
+func hashFlagContext(flagKey, contextKey string, salt uint64) uint64 {
+	// A stack-allocated digest keeps evaluation free of heap allocations
+	var h xxhash.Digest
+	h.Reset()
 	h.WriteString(flagKey)
 	h.Write([]byte{'\x1f'})
 	h.WriteString(contextKey)
-	
+
 	// Add salt if provided
 	if salt != 0 {
 		var saltBytes [8]byte
@@ -21,9 +41,6 @@ func HashFlagContext(flagKey, contextKey string, salt uint64) int {
 		}
 		h.Write(saltBytes[:])
 	}
-	
-	hash := h.Sum64()
-	// Map to [0..99] for percentage rollouts
-	return int(hash % 100)
-}
 
+	return h.Sum64()
+}
@@ -1,7 +1,10 @@
 package eval
 
 import (
+	"strconv"
 	"testing"
+
+	"github.com/0mjs/goff/internal/config"
 )
 
 func TestHashFlagContext_Deterministic(t *testing.T) {
@@ -58,3 +61,57 @@ func TestHashFlagContext_Distribution(t *testing.T) {
 	}
 }
 
+func TestHashBucket_MatchesPercent(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		key := "user:" + strconv.Itoa(i)
+		bucket := HashBucket("flag", key, 0)
+		if bucket < 0 || bucket >= config.BucketCount {
+			t.Fatalf("HashBucket() out of range: %d", bucket)
+		}
+		if got, want := bucket/config.BucketsPerPercent, HashFlagContext("flag", key, 0); got != want {
+			t.Fatalf("HashBucket()/BucketsPerPercent = %d, want %d", got, want)
+		}
+	}
+}
+
+func TestSelectVariant_Weights(t *testing.T) {
+	// A 0.1% canary should select roughly that share of contexts
+	dist := config.NewDistribution(map[string]int{"canary": 100, "stable": config.BucketCount - 100}, 1)
+	canary := 0
+	for i := 0; i < 100000; i++ {
+		res, ok := selectVariant(&config.CompiledFlag{}, "flag", Context{Key: "user:" + strconv.Itoa(i)}, split{dist: dist}, Percent, -1)
+		if !ok {
+			t.Fatalf("selectVariant() failed for user:%d", i)
+		}
+		if res.Variant == "canary" {
+			canary++
+		}
+	}
+	if canary < 50 || canary > 150 {
+		t.Errorf("canary selected %d times out of 100000, want about 100", canary)
+	}
+}
+
+func TestSelectVariant_PercentUnchanged(t *testing.T) {
+	// Whole-percentage splits must keep selecting the same contexts as
+	// bucketing into [0..99] did
+	variants := map[string]int{"a": 30, "b": 45, "c": 25}
+	dist := config.NewDistribution(variants, config.BucketsPerPercent)
+	for i := 0; i < 1000; i++ {
+		key := "user:" + strconv.Itoa(i)
+		res, _ := selectVariant(&config.CompiledFlag{}, "flag", Context{Key: key}, split{dist: dist}, Percent, -1)
+
+		var want string
+		switch p := HashFlagContext("flag", key, 0); {
+		case p < 30:
+			want = "a"
+		case p < 75:
+			want = "b"
+		default:
+			want = "c"
+		}
+		if res.Variant != want {
+			t.Fatalf("%s: got variant %q, want %q", key, res.Variant, want)
+		}
+	}
+}
@@ -1,155 +1,376 @@
 package eval
 
 import (
-	"sort"
+	"encoding/json"
+	"strconv"
 
 	"github.com/0mjs/goff/internal/config"
 )
 
+// Result describes how a flag evaluation was resolved.
+type Result struct {
+	Variant      string // selected variant; empty when a default value was used
+	Reason       Reason
+	RuleIndex    int    // index of the matching rule, or -1
+	Prerequisite string // key of the prerequisite flag that blocked evaluation
+	SkippedRule  int    // with BucketKeyFallthrough, index of the first rule skipped for lack of its bucket key
+}
+
 // EvalBool evaluates a boolean flag.
 func EvalBool(flag *config.CompiledFlag, flagKey string, ctx Context, def bool) (bool, Reason) {
-	if flag == nil {
-		return def, Missing
+	value, res := EvalBoolDetail(flag, flagKey, ctx, def)
+	return value, res.Reason
+}
+
+// EvalBoolDetail evaluates a boolean flag and reports how it was resolved.
+func EvalBoolDetail(flag *config.CompiledFlag, flagKey string, ctx Context, def bool) (bool, Result) {
+	res, ok := resolve(flag, "bool", flagKey, ctx)
+	if !ok {
+		if d, ok := defaultValue(flag, res).(bool); ok {
+			return d, res
+		}
+		return def, res
 	}
 
-	if !flag.Enabled {
-		if flag.Default != nil {
-			if d, ok := flag.Default.(bool); ok {
-				return d, Disabled
-			}
+	return res.Variant == "true", res
+}
+
+// EvalString evaluates a string flag.
+func EvalString(flag *config.CompiledFlag, flagKey string, ctx Context, def string) (string, Reason) {
+	value, res := EvalStringDetail(flag, flagKey, ctx, def)
+	return value, res.Reason
+}
+
+// EvalStringDetail evaluates a string flag and reports how it was resolved.
+func EvalStringDetail(flag *config.CompiledFlag, flagKey string, ctx Context, def string) (string, Result) {
+	res, ok := resolve(flag, "string", flagKey, ctx)
+	if !ok {
+		if d, ok := defaultValue(flag, res).(string); ok {
+			return d, res
 		}
-		return def, Disabled
+		return def, res
 	}
 
-	// Evaluate rules in order; first match wins
-	for _, rule := range flag.Rules {
-		if EvalRule(rule, ctx) {
-			// Rule matched - use rule variants
-			return selectVariantBool(flagKey, ctx.Key, rule.Variants, def)
+	return res.Variant, res
+}
+
+// EvalInt evaluates an integer flag.
+func EvalInt(flag *config.CompiledFlag, flagKey string, ctx Context, def int64) (int64, Reason) {
+	value, res := EvalIntDetail(flag, flagKey, ctx, def)
+	return value, res.Reason
+}
+
+// EvalIntDetail evaluates an integer flag and reports how it was resolved.
+func EvalIntDetail(flag *config.CompiledFlag, flagKey string, ctx Context, def int64) (int64, Result) {
+	res, ok := resolve(flag, "int", flagKey, ctx)
+	if !ok {
+		if d, ok := defaultValue(flag, res).(int64); ok {
+			return d, res
 		}
+		return def, res
 	}
 
-	// No rule matched - fall back to percentage rollout
-	if len(flag.Variants) > 0 {
-		return selectVariantBool(flagKey, ctx.Key, flag.Variants, def)
+	if v, ok := flag.Values[res.Variant].(int64); ok {
+		return v, res
+	}
+	v, err := strconv.ParseInt(res.Variant, 10, 64)
+	if err != nil {
+		res.Reason = Error
+		return def, res
 	}
+	return v, res
+}
+
+// EvalFloat evaluates a floating-point flag.
+func EvalFloat(flag *config.CompiledFlag, flagKey string, ctx Context, def float64) (float64, Reason) {
+	value, res := EvalFloatDetail(flag, flagKey, ctx, def)
+	return value, res.Reason
+}
 
-	// No variants defined - use default
-	if flag.Default != nil {
-		if d, ok := flag.Default.(bool); ok {
-			return d, Default
+// EvalFloatDetail evaluates a floating-point flag and reports how it was
+// resolved.
+func EvalFloatDetail(flag *config.CompiledFlag, flagKey string, ctx Context, def float64) (float64, Result) {
+	res, ok := resolve(flag, "float", flagKey, ctx)
+	if !ok {
+		if d, ok := defaultValue(flag, res).(float64); ok {
+			return d, res
 		}
+		return def, res
 	}
 
-	return def, Default
+	if v, ok := flag.Values[res.Variant].(float64); ok {
+		return v, res
+	}
+	v, err := strconv.ParseFloat(res.Variant, 64)
+	if err != nil {
+		res.Reason = Error
+		return def, res
+	}
+	return v, res
 }
 
-// EvalString evaluates a string flag.
-func EvalString(flag *config.CompiledFlag, flagKey string, ctx Context, def string) (string, Reason) {
+// EvalJSON evaluates a JSON flag. The selected variant, or the flag's default
+// variant, is reported in Result.Variant and its payload is in flag.Payloads.
+// ok is false if the evaluation produced neither.
+func EvalJSON(flag *config.CompiledFlag, flagKey string, ctx Context) (Result, bool) {
+	res, ok := resolve(flag, "json", flagKey, ctx)
+	if !ok {
+		if d, ok := defaultValue(flag, res).(string); ok {
+			res.Variant = d
+			return res, true
+		}
+		return res, false
+	}
+
+	return res, true
+}
+
+// EvalValue evaluates a flag of any type. The value is a bool, string, int64
+// or float64, or a copy of the decoded payload for json flags; it is nil if
+// no value applies.
+func EvalValue(flag *config.CompiledFlag, flagKey string, ctx Context) (any, Result) {
+	if flag == nil {
+		return nil, Result{Reason: Missing, RuleIndex: -1}
+	}
+
+	switch flag.Type {
+	case "bool":
+		return EvalBoolDetail(flag, flagKey, ctx, false)
+	case "string":
+		return EvalStringDetail(flag, flagKey, ctx, "")
+	case "int":
+		return EvalIntDetail(flag, flagKey, ctx, 0)
+	case "float":
+		return EvalFloatDetail(flag, flagKey, ctx, 0)
+	case "json":
+		res, ok := EvalJSON(flag, flagKey, ctx)
+		if !ok {
+			return nil, res
+		}
+		// The payload is shared by every evaluation of the snapshot
+		return config.CloneJSON(flag.Values[res.Variant]), res
+	}
+
+	return nil, Result{Reason: TypeMismatch, RuleIndex: -1}
+}
+
+// defaultValue returns the flag's own default if the reason allows one.
+// Missing flags, type mismatches and errors fall back to the caller's default.
+func defaultValue(flag *config.CompiledFlag, res Result) any {
+	switch res.Reason {
+	case Disabled, Default, MissingBucketKey, Inactive, PrerequisiteFailed, BucketKeyFallthrough:
+		return flag.Default
+	}
+	return nil
+}
+
+// resolve runs the targeting logic of a flag and returns the selected
+// variant. ok is false when the caller should fall back to a default value;
+// the result's reason explains why.
+func resolve(flag *config.CompiledFlag, flagType, flagKey string, ctx Context) (Result, bool) {
 	if flag == nil {
-		return def, Missing
+		return Result{Reason: Missing, RuleIndex: -1}, false
+	}
+
+	if flag.Type != flagType {
+		return Result{Reason: TypeMismatch, RuleIndex: -1}, false
 	}
 
 	if !flag.Enabled {
-		if flag.Default != nil {
-			if d, ok := flag.Default.(string); ok {
-				return d, Disabled
-			}
+		return Result{Reason: Disabled, RuleIndex: -1}, false
+	}
+
+	if flag.Schedule != nil && !flag.Schedule.Active(ctx.now()) {
+		return Result{Reason: Inactive, RuleIndex: -1}, false
+	}
+
+	for i := range flag.Prerequisites {
+		pre := &flag.Prerequisites[i]
+		if !prerequisiteMet(pre, ctx) {
+			return Result{Reason: PrerequisiteFailed, RuleIndex: -1, Prerequisite: pre.Key}, false
+		}
+	}
+
+	// Individually targeted keys take precedence over rules
+	if len(flag.Targets) > 0 {
+		if variant, ok := flag.Targets[ctx.Key]; ok {
+			return Result{Variant: variant, Reason: TargetMatch, RuleIndex: -1}, true
 		}
-		return def, Disabled
 	}
 
 	// Evaluate rules in order; first match wins
-	for _, rule := range flag.Rules {
+	skipped := -1
+	for i, rule := range flag.Rules {
+		if rule.Schedule != nil && !rule.Schedule.Active(ctx.now()) {
+			continue
+		}
 		if EvalRule(rule, ctx) {
 			// Rule matched - use rule variants
-			return selectVariantString(flagKey, ctx.Key, rule.Variants, def)
+			res, ok := selectVariant(flag, flagKey, ctx, ruleSplit(rule, i), Match, i)
+			if res.Reason != MissingBucketKey {
+				return res, ok
+			}
+			// The context lacks the rule's bucket_by attribute; try the next rule
+			if skipped < 0 {
+				skipped = i
+			}
 		}
 	}
 
-	// No rule matched - fall back to percentage rollout
-	if len(flag.Variants) > 0 {
-		return selectVariantString(flagKey, ctx.Key, flag.Variants, def)
+	// No rule matched - fall back to percentage rollout, or the default if
+	// no variants are defined
+	res, ok := Result{Reason: Default, RuleIndex: -1}, false
+	if s := flagSplit(flag); s.dist != nil {
+		res, ok = selectVariant(flag, flagKey, ctx, s, Percent, -1)
+	}
+	if skipped >= 0 && (res.Reason == Percent || res.Reason == Default) {
+		res.Reason = BucketKeyFallthrough
+		res.SkippedRule = skipped
 	}
+	return res, ok
+}
 
-	// No variants defined - use default
-	if flag.Default != nil {
-		if d, ok := flag.Default.(string); ok {
-			return d, Default
-		}
+// prerequisiteMet evaluates the prerequisite flag for ctx and reports whether
+// it produced the required variant, either by selecting it or as its default.
+func prerequisiteMet(pre *config.CompiledPrerequisite, ctx Context) bool {
+	if pre.Flag == nil {
+		return false
+	}
+
+	res, ok := resolve(pre.Flag, pre.Flag.Type, pre.Key, ctx)
+	if !ok {
+		return defaultValue(pre.Flag, res) == pre.Value
 	}
+	if pre.Flag.Type == "json" {
+		return res.Variant == pre.Variant
+	}
+	return pre.Flag.Values[res.Variant] == pre.Value
+}
 
-	return def, Default
+// split gathers what selectVariant needs from a flag or rule.
+type split struct {
+	dist     *config.Distribution
+	rollout  *config.CompiledRollout
+	bucketBy string
+	salt     uint64
+	rule     int    // index of the rule the split belongs to, or -1 for the flag's own split
+	ruleID   string // id of that rule, if it has one
 }
 
-// selectVariantBool selects a boolean variant based on percentage rollout.
-func selectVariantBool(flagKey, contextKey string, variants map[string]int, def bool) (bool, Reason) {
-	if len(variants) == 0 {
-		return def, Default
+func flagSplit(flag *config.CompiledFlag) split {
+	return split{
+		dist:     flag.Split(),
+		rollout:  flag.Rollout,
+		bucketBy: flag.BucketBy,
+		salt:     flag.Salt,
+		rule:     -1,
 	}
+}
+
+func ruleSplit(rule *config.CompiledRule, index int) split {
+	return split{
+		dist:     rule.Split(),
+		rollout:  rule.Rollout,
+		bucketBy: rule.BucketBy,
+		salt:     rule.Salt,
+		rule:     index,
+		ruleID:   rule.ID,
+	}
+}
 
-	bucket := HashFlagContext(flagKey, contextKey, 0)
-	
-	// Sort variants for deterministic iteration
-	type variantPct struct {
-		variant string
-		pct     int
+// selectVariant selects a variant based on percentage rollout, bucketing the
+// context by s.bucketBy (or its key) mixed with s.salt. An active rollout
+// replaces the configured percentages, and for sticky flags a variant
+// recorded in ctx.Sticky takes precedence. reason and ruleIndex describe
+// where the variants came from.
+func selectVariant(flag *config.CompiledFlag, flagKey string, ctx Context, s split, reason Reason, ruleIndex int) (Result, bool) {
+	if s.dist == nil {
+		return Result{Reason: Default, RuleIndex: ruleIndex}, false
 	}
-	var sorted []variantPct
-	for variant, pct := range variants {
-		sorted = append(sorted, variantPct{variant, pct})
+
+	ramp := 0
+	if s.rollout != nil {
+		ramp = s.rollout.Buckets(ctx.now())
+	} else if s.dist.IsSingle {
+		// A single variant at 100% needs no bucketing
+		return Result{Variant: s.dist.Single, Reason: reason, RuleIndex: ruleIndex}, true
 	}
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].variant < sorted[j].variant
-	})
-	
-	// Calculate cumulative percentages
-	var cumulative int
-	for _, v := range sorted {
-		cumulative += v.pct
-		if bucket < cumulative {
-			if v.variant == "true" {
-				return true, Match
-			}
-			return false, Match
+
+	key, ok := bucketKey(ctx, s.bucketBy)
+	if !ok {
+		return Result{Reason: MissingBucketKey, RuleIndex: ruleIndex}, false
+	}
+
+	sticky := flag.Sticky && ctx.Sticky != nil
+	var stickyKey string
+	if sticky {
+		stickyKey = s.stickyKey(key)
+		if variant, ok := stickyVariant(ctx.Sticky, flagKey, stickyKey, s.salt); ok && s.covers(variant, ramp) {
+			return Result{Variant: variant, Reason: reason, RuleIndex: ruleIndex}, true
 		}
 	}
 
-	// Fallback (shouldn't happen if percentages sum to 100)
-	return def, Percent
+	bucket := HashBucket(flagKey, key, s.salt)
+	variant, ok := s.pick(bucket, ramp)
+	if !ok {
+		// Shouldn't happen if the split covers every bucket
+		return Result{Reason: Error, RuleIndex: ruleIndex}, false
+	}
+
+	if sticky {
+		// Best effort: a failed write only means the next evaluation hashes again
+		_ = ctx.Sticky.Set(flagKey, stickyKey, s.salt, variant)
+	}
+
+	return Result{Variant: variant, Reason: reason, RuleIndex: ruleIndex}, true
 }
 
-// selectVariantString selects a string variant based on percentage rollout.
-func selectVariantString(flagKey, contextKey string, variants map[string]int, def string) (string, Reason) {
-	if len(variants) == 0 {
-		return def, Default
+// stickyKey returns the key a context's assignment by this split is stored
+// under. Rules inherit the flag's salt, so without the rule's id a variant
+// assigned by one split would override the percentages of another.
+func (s split) stickyKey(key string) string {
+	switch {
+	case s.rule < 0:
+		return key
+	case s.ruleID != "":
+		return key + "\x1frule:" + s.ruleID
 	}
+	// Rules built without config.Compile, which requires ids on the rules of
+	// sticky flags
+	return key + "\x1frule#" + strconv.Itoa(s.rule)
+}
 
-	bucket := HashFlagContext(flagKey, contextKey, 0)
-	
-	// Sort variants for deterministic iteration
-	type variantPct struct {
-		variant string
-		pct     int
+// pick returns the variant for bucket, ramp being the rollout's current size.
+func (s split) pick(bucket, ramp int) (string, bool) {
+	if s.rollout != nil {
+		return s.rollout.Select(bucket, ramp)
 	}
-	var sorted []variantPct
-	for variant, pct := range variants {
-		sorted = append(sorted, variantPct{variant, pct})
+	return s.dist.Select(bucket)
+}
+
+// covers reports whether the split currently gives variant any buckets.
+func (s split) covers(variant string, ramp int) bool {
+	if s.rollout != nil {
+		return s.rollout.Covers(variant, ramp)
 	}
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].variant < sorted[j].variant
-	})
-	
-	// Calculate cumulative percentages
-	var cumulative int
-	for _, v := range sorted {
-		cumulative += v.pct
-		if bucket < cumulative {
-			return v.variant, Match
-		}
+	return s.dist.Covers(variant)
+}
+
+// bucketKey returns the value a context is bucketed by: its key, or the
+// attribute named by bucketBy. ok is false if the attribute is missing, empty
+// or not a scalar.
+func bucketKey(ctx Context, bucketBy string) (string, bool) {
+	if bucketBy == "" {
+		return ctx.Key, true
 	}
 
-	// Fallback (shouldn't happen if percentages sum to 100)
-	return def, Percent
+	switch v := ctx.Attrs[bucketBy].(type) {
+	case string:
+		return v, v != ""
+	case json.Number:
+		return string(v), v != ""
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return config.FormatValue(v), true
+	}
+	return "", false
 }
-
@@ -1,11 +1,26 @@
 package eval
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/0mjs/goff/internal/config"
 )
 
+// compileFlag compiles a single flag the way the loader does, so that
+// benchmarks measure the precomputed evaluation path.
+func compileFlag(tb testing.TB, flag config.Flag) *config.CompiledFlag {
+	tb.Helper()
+	compiled, err := config.Compile(&config.Config{
+		Version: 1,
+		Flags:   map[string]config.Flag{"test_flag": flag},
+	})
+	if err != nil {
+		tb.Fatalf("Compile() error = %v", err)
+	}
+	return compiled.Flags["test_flag"]
+}
+
 func BenchmarkEvalBool(b *testing.B) {
 	flag := &config.CompiledFlag{
 		Enabled: true,
@@ -30,6 +45,31 @@ func BenchmarkEvalBool(b *testing.B) {
 	}
 }
 
+func BenchmarkEvalBool_Compiled(b *testing.B) {
+	flag := compileFlag(b, config.Flag{
+		Enabled: true,
+		Type:    "bool",
+		Variants: map[string]int{
+			"true":  50,
+			"false": 50,
+		},
+		Default: false,
+	})
+
+	ctx := Context{
+		Key: "user:123",
+		Attrs: map[string]any{
+			"plan": "pro",
+		},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = EvalBool(flag, "test_flag", ctx, false)
+	}
+}
+
 func BenchmarkEvalBool_WithRule(b *testing.B) {
 	flag := &config.CompiledFlag{
 		Enabled: true,
@@ -65,6 +105,69 @@ func BenchmarkEvalBool_WithRule(b *testing.B) {
 	}
 }
 
+func BenchmarkEvalBool_CompiledWithRule(b *testing.B) {
+	flag := compileFlag(b, config.Flag{
+		Enabled: true,
+		Type:    "bool",
+		Variants: map[string]int{
+			"true":  50,
+			"false": 50,
+		},
+		Rules: []config.Rule{
+			{
+				When: config.WhenCondition{
+					All: []config.AttributeCondition{
+						{Attr: "plan", Op: "eq", Value: "pro"},
+					},
+				},
+				Then: config.ThenAction{
+					Variants: map[string]int{
+						"true":  90,
+						"false": 10,
+					},
+				},
+			},
+		},
+		Default: false,
+	})
+
+	ctx := Context{
+		Key: "user:123",
+		Attrs: map[string]any{
+			"plan": "pro",
+		},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = EvalBool(flag, "test_flag", ctx, false)
+	}
+}
+
+func BenchmarkEvalBool_SingleVariant(b *testing.B) {
+	flag := compileFlag(b, config.Flag{
+		Enabled: true,
+		Type:    "bool",
+		Variants: map[string]int{
+			"true":  100,
+			"false": 0,
+		},
+		Default: false,
+	})
+
+	ctx := Context{
+		Key:   "user:123",
+		Attrs: map[string]any{},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = EvalBool(flag, "test_flag", ctx, false)
+	}
+}
+
 func BenchmarkEvalString(b *testing.B) {
 	flag := &config.CompiledFlag{
 		Enabled: true,
@@ -88,6 +191,30 @@ func BenchmarkEvalString(b *testing.B) {
 	}
 }
 
+func BenchmarkEvalString_Compiled(b *testing.B) {
+	flag := compileFlag(b, config.Flag{
+		Enabled: true,
+		Type:    "string",
+		Variants: map[string]int{
+			"red":   40,
+			"blue":  30,
+			"green": 30,
+		},
+		Default: "red",
+	})
+
+	ctx := Context{
+		Key:   "user:123",
+		Attrs: map[string]any{},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = EvalString(flag, "test_flag", ctx, "default")
+	}
+}
+
 func BenchmarkEvalBool_Concurrent(b *testing.B) {
 	flag := &config.CompiledFlag{
 		Enabled: true,
@@ -110,6 +237,29 @@ func BenchmarkEvalBool_Concurrent(b *testing.B) {
 	})
 }
 
+func BenchmarkEvalBool_CompiledConcurrent(b *testing.B) {
+	flag := compileFlag(b, config.Flag{
+		Enabled: true,
+		Type:    "bool",
+		Variants: map[string]int{
+			"true":  50,
+			"false": 50,
+		},
+		Default: false,
+	})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := Context{
+			Key:   "user:123",
+			Attrs: map[string]any{},
+		}
+		for pb.Next() {
+			_, _ = EvalBool(flag, "test_flag", ctx, false)
+		}
+	})
+}
+
 func BenchmarkEvalString_Concurrent(b *testing.B) {
 	flag := &config.CompiledFlag{
 		Enabled: true,
@@ -131,3 +281,130 @@ func BenchmarkEvalString_Concurrent(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkEvalString_CompiledConcurrent(b *testing.B) {
+	flag := compileFlag(b, config.Flag{
+		Enabled: true,
+		Type:    "string",
+		Variants: map[string]int{
+			"red":  50,
+			"blue": 50,
+		},
+		Default: "red",
+	})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := Context{
+			Key:   "user:123",
+			Attrs: map[string]any{},
+		}
+		for pb.Next() {
+			_, _ = EvalString(flag, "test_flag", ctx, "default")
+		}
+	})
+}
+
+func BenchmarkEvalBool_LargeTargets(b *testing.B) {
+	keys := make([]string, 50000)
+	for i := range keys {
+		keys[i] = "user:" + strconv.Itoa(i)
+	}
+	flag := compileFlag(b, config.Flag{
+		Enabled:  true,
+		Type:     "bool",
+		Variants: map[string]int{"true": 50, "false": 50},
+		Targets:  map[string][]string{"true": keys},
+		Default:  false,
+	})
+
+	targeted := Context{Key: "user:49999"}
+	untargeted := Context{Key: "user:anonymous"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = EvalBool(flag, "test_flag", targeted, false)
+		_, _ = EvalBool(flag, "test_flag", untargeted, false)
+	}
+}
+
+// ruleHeavyFlag has many rules mixing the common operators, of which only
+// the last matches.
+func ruleHeavyFlag(tb testing.TB) *config.CompiledFlag {
+	countries := make([]any, 0, 20)
+	for i := 0; i < 20; i++ {
+		countries = append(countries, "c"+strconv.Itoa(i))
+	}
+
+	var rules []config.Rule
+	for i := 0; i < 20; i++ {
+		rules = append(rules, config.Rule{
+			When: config.WhenCondition{
+				All: []config.AttributeCondition{
+					{Attr: "seats", Op: "gte", Value: "10"},
+					{Attr: "country", Op: "in", Value: countries},
+					{Attr: "email", Op: "contains", Value: "@example.com"},
+					{Attr: "plan", Op: "eq", Value: "plan" + strconv.Itoa(i)},
+				},
+			},
+			Then: config.ThenAction{Variants: map[string]int{"true": 100}},
+		})
+	}
+	rules = append(rules, config.Rule{
+		When: config.WhenCondition{
+			Any: []config.AttributeCondition{
+				{Attr: "seats", Op: "eq", Value: 40},
+			},
+		},
+		Then: config.ThenAction{Variants: map[string]int{"false": 100}},
+	})
+
+	return compileFlag(tb, config.Flag{
+		Enabled:  true,
+		Type:     "bool",
+		Variants: map[string]int{"true": 50, "false": 50},
+		Rules:    rules,
+		Default:  false,
+	})
+}
+
+func BenchmarkEvalBool_ManyRules(b *testing.B) {
+	flag := ruleHeavyFlag(b)
+	ctx := Context{
+		Key: "user:123",
+		Attrs: map[string]any{
+			"plan":    "pro",
+			"seats":   40,
+			"country": "c19",
+			"email":   "jane@example.com",
+		},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = EvalBool(flag, "test_flag", ctx, false)
+	}
+}
+
+func BenchmarkEvalBool_ManyRulesConcurrent(b *testing.B) {
+	flag := ruleHeavyFlag(b)
+	ctx := Context{
+		Key: "user:123",
+		Attrs: map[string]any{
+			"plan":    "pro",
+			"seats":   "40",
+			"country": "c19",
+			"email":   "jane@example.com",
+		},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = EvalBool(flag, "test_flag", ctx, false)
+		}
+	})
+}
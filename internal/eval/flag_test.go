@@ -1,7 +1,10 @@
 package eval
 
 import (
+	"encoding/json"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/0mjs/goff/internal/config"
 )
@@ -184,3 +187,795 @@ func TestEvalString_WithRule(t *testing.T) {
 		t.Errorf("EvalString() reason = %v, want Match", reason)
 	}
 }
+
+func TestEvalInt_WithRule(t *testing.T) {
+	flag := &config.CompiledFlag{
+		Enabled: true,
+		Type:    "int",
+		Variants: map[string]int{
+			"10": 100,
+		},
+		Values: map[string]any{
+			"10":  int64(10),
+			"500": int64(500),
+		},
+		Rules: []*config.CompiledRule{
+			{
+				Conditions: []*config.CompiledCondition{
+					{Attr: "plan", Op: "eq", Value: "pro", IsAll: true},
+				},
+				Variants: map[string]int{
+					"500": 100,
+				},
+			},
+		},
+		Default: int64(1),
+	}
+
+	ctx := Context{Key: "user:1", Attrs: map[string]any{"plan": "pro"}}
+	result, reason := EvalInt(flag, "test", ctx, 0)
+	if result != 500 {
+		t.Errorf("EvalInt() = %v, want 500 (rule match)", result)
+	}
+	if reason != Match {
+		t.Errorf("EvalInt() reason = %v, want Match", reason)
+	}
+
+	ctx.Attrs["plan"] = "basic"
+	result, _ = EvalInt(flag, "test", ctx, 0)
+	if result != 10 {
+		t.Errorf("EvalInt() = %v, want 10 (percentage rollout)", result)
+	}
+}
+
+func TestEvalInt_Disabled(t *testing.T) {
+	flag := &config.CompiledFlag{
+		Enabled: false,
+		Type:    "int",
+		Default: int64(42),
+	}
+
+	ctx := Context{Key: "user:1", Attrs: map[string]any{}}
+
+	result, reason := EvalInt(flag, "test", ctx, 7)
+
+	if result != 42 {
+		t.Errorf("EvalInt() = %v, want 42 (default)", result)
+	}
+	if reason != Disabled {
+		t.Errorf("EvalInt() reason = %v, want Disabled", reason)
+	}
+}
+
+func TestEvalFloat(t *testing.T) {
+	flag := &config.CompiledFlag{
+		Enabled: true,
+		Type:    "float",
+		Variants: map[string]int{
+			"0.25": 100,
+		},
+		Default: 1.0,
+	}
+
+	ctx := Context{Key: "user:1", Attrs: map[string]any{}}
+
+	result, reason := EvalFloat(flag, "test", ctx, 0)
+	if result != 0.25 {
+		t.Errorf("EvalFloat() = %v, want 0.25", result)
+	}
+	if reason != Percent {
+		t.Errorf("EvalFloat() reason = %v, want Percent", reason)
+	}
+
+	result, reason = EvalFloat(nil, "test", ctx, 2.5)
+	if result != 2.5 || reason != Missing {
+		t.Errorf("EvalFloat(nil) = %v, %v, want 2.5, Missing", result, reason)
+	}
+}
+
+func TestEvalJSON(t *testing.T) {
+	flag := &config.CompiledFlag{
+		Enabled: true,
+		Type:    "json",
+		Rules: []*config.CompiledRule{
+			{
+				Conditions: []*config.CompiledCondition{
+					{Attr: "plan", Op: "eq", Value: "pro", IsAll: true},
+				},
+				Variants: map[string]int{
+					"premium": 100,
+				},
+			},
+		},
+		Default: "basic",
+	}
+
+	ctx := Context{Key: "user:1", Attrs: map[string]any{"plan": "pro"}}
+	res, ok := EvalJSON(flag, "test", ctx)
+	if !ok || res.Variant != "premium" || res.Reason != Match {
+		t.Errorf("EvalJSON() = %+v, %v, want premium, Match, true", res, ok)
+	}
+
+	ctx.Attrs["plan"] = "basic"
+	res, ok = EvalJSON(flag, "test", ctx)
+	if !ok || res.Variant != "basic" || res.Reason != Default {
+		t.Errorf("EvalJSON() = %+v, %v, want basic, Default, true", res, ok)
+	}
+
+	res, ok = EvalJSON(nil, "test", ctx)
+	if ok || res.Reason != Missing {
+		t.Errorf("EvalJSON(nil) = %+v, %v, want Missing, false", res, ok)
+	}
+}
+
+func TestEvalBoolDetail_Reasons(t *testing.T) {
+	flag := &config.CompiledFlag{
+		Enabled: true,
+		Type:    "bool",
+		Variants: map[string]int{
+			"true":  0,
+			"false": 100,
+		},
+		Rules: []*config.CompiledRule{
+			{
+				Conditions: []*config.CompiledCondition{
+					{Attr: "plan", Op: "eq", Value: "free", IsAll: true},
+				},
+				Variants: map[string]int{"false": 100},
+			},
+			{
+				Conditions: []*config.CompiledCondition{
+					{Attr: "plan", Op: "eq", Value: "pro", IsAll: true},
+				},
+				Variants: map[string]int{"true": 100},
+			},
+		},
+		Default: false,
+	}
+
+	tests := []struct {
+		name string
+		flag *config.CompiledFlag
+		ctx  Context
+		want Result
+	}{
+		{
+			name: "rule match",
+			flag: flag,
+			ctx:  Context{Key: "user:1", Attrs: map[string]any{"plan": "pro"}},
+			want: Result{Variant: "true", Reason: Match, RuleIndex: 1},
+		},
+		{
+			name: "fallthrough split",
+			flag: flag,
+			ctx:  Context{Key: "user:1", Attrs: map[string]any{"plan": "basic"}},
+			want: Result{Variant: "false", Reason: Percent, RuleIndex: -1},
+		},
+		{
+			name: "type mismatch",
+			flag: &config.CompiledFlag{Enabled: true, Type: "string", Default: "x"},
+			ctx:  Context{Key: "user:1"},
+			want: Result{Reason: TypeMismatch, RuleIndex: -1},
+		},
+		{
+			name: "missing",
+			flag: nil,
+			ctx:  Context{Key: "user:1"},
+			want: Result{Reason: Missing, RuleIndex: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := EvalBoolDetail(tt.flag, "test", tt.ctx, false)
+			if got != tt.want {
+				t.Errorf("EvalBoolDetail() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEval_BucketBy(t *testing.T) {
+	flag := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		BucketBy: "org_id",
+		Variants: map[string]int{"a": 50, "b": 50},
+		Default:  "none",
+	})
+
+	// Every user of an organization gets the same variant
+	want, res := EvalStringDetail(flag, "test", Context{Key: "user:1", Attrs: map[string]any{"org_id": "acme"}}, "")
+	if res.Reason != Percent {
+		t.Fatalf("EvalStringDetail() reason = %v, want Percent", res.Reason)
+	}
+	for i := 2; i < 50; i++ {
+		ctx := Context{Key: "user:" + strconv.Itoa(i), Attrs: map[string]any{"org_id": "acme"}}
+		if got, _ := EvalString(flag, "test", ctx, ""); got != want {
+			t.Fatalf("user:%d got %q, want %q", i, got, want)
+		}
+	}
+
+	// Numeric attributes bucket like their decimal form
+	byInt, _ := EvalString(flag, "test", Context{Attrs: map[string]any{"org_id": 42}}, "")
+	byString, _ := EvalString(flag, "test", Context{Attrs: map[string]any{"org_id": "42"}}, "")
+	if byInt != byString {
+		t.Errorf("org_id 42 got %q, org_id \"42\" got %q", byInt, byString)
+	}
+	for _, id := range []any{int32(42), uint(42), uint8(42), float32(42), 42.0, json.Number("42")} {
+		got, res := EvalStringDetail(flag, "test", Context{Attrs: map[string]any{"org_id": id}}, "")
+		if got != byString || res.Reason != Percent {
+			t.Errorf("org_id %T(42) got %q, %v, want %q, Percent", id, got, res.Reason, byString)
+		}
+	}
+
+	got, res := EvalStringDetail(flag, "test", Context{Key: "user:1"}, "caller")
+	if got != "none" || res.Reason != MissingBucketKey {
+		t.Errorf("EvalStringDetail() = %q, %+v, want flag default with MissingBucketKey", got, res)
+	}
+}
+
+func TestEval_Salt(t *testing.T) {
+	unsalted := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Variants: map[string]int{"a": 50, "b": 50},
+	})
+	salted := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Salt:     "experiment-2",
+		Variants: map[string]int{"a": 50, "b": 50},
+	})
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		ctx := Context{Key: "user:" + strconv.Itoa(i)}
+		a, _ := EvalString(unsalted, "test", ctx, "")
+		b, _ := EvalString(salted, "test", ctx, "")
+		if a != b {
+			moved++
+		}
+	}
+	// Salting reshuffles about half of the contexts between two even variants
+	if moved < 400 || moved > 600 {
+		t.Errorf("salt moved %d of 1000 contexts, want about 500", moved)
+	}
+}
+
+func TestEval_RuleBucketBy(t *testing.T) {
+	enterprise := config.Rule{
+		When: config.WhenCondition{
+			All: []config.AttributeCondition{{Attr: "plan", Op: "eq", Value: "enterprise"}},
+		},
+		Then: config.ThenAction{
+			Variants: map[string]int{"true": 20, "false": 80},
+			BucketBy: "org_id",
+			Salt:     "enterprise-rollout",
+		},
+	}
+	flag := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "bool",
+		Variants: map[string]int{"true": 0, "false": 100},
+		Rules:    []config.Rule{enterprise},
+		Default:  false,
+	})
+
+	// The fallthrough still buckets by key
+	if _, res := EvalBoolDetail(flag, "test", Context{Key: "user:1"}, true); res.Reason != Percent {
+		t.Errorf("fallthrough reason = %v, want Percent", res.Reason)
+	}
+
+	// Every user of an org gets the org's variant
+	for org := 0; org < 50; org++ {
+		orgID := "org:" + strconv.Itoa(org)
+		first, _ := EvalBoolDetail(flag, "test", Context{Key: "user:0", Attrs: map[string]any{"plan": "enterprise", "org_id": orgID}}, false)
+		for user := 1; user < 10; user++ {
+			ctx := Context{Key: "user:" + strconv.Itoa(user), Attrs: map[string]any{"plan": "enterprise", "org_id": orgID}}
+			if got, res := EvalBoolDetail(flag, "test", ctx, false); got != first || res.Reason != Match {
+				t.Fatalf("%s user %d = %v (%v), want %v like user 0", orgID, user, got, res.Reason, first)
+			}
+		}
+	}
+
+	// Without the attribute the rule is skipped and the fallthrough decides
+	ctx := Context{Key: "user:1", Attrs: map[string]any{"plan": "enterprise"}}
+	if got, res := EvalBoolDetail(flag, "test", ctx, true); got || res.Variant != "false" || res.Reason != BucketKeyFallthrough || res.RuleIndex != -1 || res.SkippedRule != 0 {
+		t.Errorf("EvalBoolDetail() = %v, %+v, want fallthrough split with BucketKeyFallthrough skipping rule 0", got, res)
+	}
+
+	// ...or the next matching rule
+	withNext := compileFlag(t, config.Flag{
+		Enabled: true,
+		Type:    "bool",
+		Rules: []config.Rule{
+			enterprise,
+			{
+				When: config.WhenCondition{
+					All: []config.AttributeCondition{{Attr: "plan", Op: "in", Value: []any{"pro", "enterprise"}}},
+				},
+				Then: config.ThenAction{Variants: map[string]int{"true": 100}},
+			},
+		},
+		Default: false,
+	})
+	if got, res := EvalBoolDetail(withNext, "test", ctx, false); !got || res.Reason != Match || res.RuleIndex != 1 {
+		t.Errorf("EvalBoolDetail() = %v, %+v, want true from rule 1", got, res)
+	}
+
+	// ...or, with no fallthrough split, the flag default
+	noSplit := compileFlag(t, config.Flag{
+		Enabled: true,
+		Type:    "bool",
+		Rules: []config.Rule{
+			{
+				When: config.WhenCondition{
+					All: []config.AttributeCondition{{Attr: "plan", Op: "eq", Value: "free"}},
+				},
+				Then: config.ThenAction{Variants: map[string]int{"false": 100}},
+			},
+			enterprise,
+		},
+		Default: true,
+	})
+	if got, res := EvalBoolDetail(noSplit, "test", ctx, false); !got || res.Reason != BucketKeyFallthrough || res.RuleIndex != -1 || res.SkippedRule != 1 {
+		t.Errorf("EvalBoolDetail() = %v, %+v, want flag default with BucketKeyFallthrough skipping rule 1", got, res)
+	}
+}
+
+// mapStickyStore is a minimal StickyStore for tests.
+type mapStickyStore map[string]string
+
+func (m mapStickyStore) Get(flagKey, key string, salt uint64) (string, bool, error) {
+	v, ok := m[flagKey+"/"+key+"/"+strconv.FormatUint(salt, 10)]
+	return v, ok, nil
+}
+
+func (m mapStickyStore) Set(flagKey, key string, salt uint64, variant string) error {
+	m[flagKey+"/"+key+"/"+strconv.FormatUint(salt, 10)] = variant
+	return nil
+}
+
+func (m mapStickyStore) Reset(string) error {
+	clear(m)
+	return nil
+}
+
+func TestEval_Sticky(t *testing.T) {
+	before := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Sticky:   true,
+		Variants: map[string]int{"a": 50, "b": 50},
+	})
+	after := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Sticky:   true,
+		Variants: map[string]int{"a": 10, "b": 90},
+	})
+	unsticky := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Variants: map[string]int{"a": 10, "b": 90},
+	})
+	resalted := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Sticky:   true,
+		Salt:     "v2",
+		Variants: map[string]int{"a": 10, "b": 90},
+	})
+
+	store := mapStickyStore{}
+	assigned := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		ctx := Context{Key: "user:" + strconv.Itoa(i), Sticky: store}
+		assigned[ctx.Key], _ = EvalString(before, "test", ctx, "")
+	}
+	if len(store) != 200 {
+		t.Fatalf("store has %d assignments, want 200", len(store))
+	}
+
+	moved, resalt := 0, 0
+	for key, want := range assigned {
+		ctx := Context{Key: key, Sticky: store}
+		if got, _ := EvalString(after, "test", ctx, ""); got != want {
+			t.Fatalf("%s: sticky flag moved from %q to %q", key, want, got)
+		}
+		if got, _ := EvalString(unsticky, "test", ctx, ""); got != want {
+			moved++
+		}
+		if got, _ := EvalString(resalted, "test", ctx, ""); got != want {
+			resalt++
+		}
+	}
+	if moved == 0 {
+		t.Error("non-sticky flag should reassign contexts when the split changes")
+	}
+	if resalt == 0 {
+		t.Error("changing the salt should discard sticky assignments")
+	}
+
+	// A variant that no longer gets any traffic is not kept
+	gone := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Sticky:   true,
+		Variants: map[string]int{"a": 0, "b": 100},
+	})
+	for key := range assigned {
+		if got, _ := EvalString(gone, "test", Context{Key: key, Sticky: store}, ""); got != "b" {
+			t.Fatalf("%s: got %q, want b", key, got)
+		}
+	}
+}
+
+func TestEval_StickyPerSplit(t *testing.T) {
+	beta := config.Rule{
+		ID: "beta",
+		When: config.WhenCondition{
+			All: []config.AttributeCondition{{Attr: "plan", Op: "eq", Value: "beta"}},
+		},
+		Then: config.ThenAction{Variants: map[string]int{"true": 99, "false": 1}},
+	}
+	flag := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "bool",
+		Sticky:   true,
+		Variants: map[string]int{"true": 1, "false": 99},
+		Rules:    []config.Rule{beta},
+		Default:  false,
+	})
+
+	store := mapStickyStore{}
+	assigned := make(map[string]bool)
+	fromRule := 0
+	for i := 0; i < 200; i++ {
+		key := "user:" + strconv.Itoa(i)
+		EvalBool(flag, "test", Context{Key: key, Sticky: store}, false)
+
+		// Moving into the rule uses the rule's split, not the fallthrough's assignment
+		ctx := Context{Key: key, Attrs: map[string]any{"plan": "beta"}, Sticky: store}
+		got, res := EvalBoolDetail(flag, "test", ctx, false)
+		if res.Reason != Match || res.RuleIndex != 0 {
+			t.Fatalf("%s: result = %+v, want Match for rule 0", key, res)
+		}
+		if got {
+			fromRule++
+		}
+		if again, _ := EvalBool(flag, "test", ctx, false); again != got {
+			t.Fatalf("%s: rule assignment moved from %v to %v", key, got, again)
+		}
+		assigned[key] = got
+	}
+	if fromRule < 190 {
+		t.Errorf("%d of 200 contexts got true from the 99%% rule, want nearly all", fromRule)
+	}
+	if len(store) != 400 {
+		t.Errorf("store has %d assignments, want 400 (one per split and context)", len(store))
+	}
+
+	// Assignments follow the rule's id, not its position
+	beta.Then.Variants = map[string]int{"true": 50, "false": 50}
+	reordered := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "bool",
+		Sticky:   true,
+		Variants: map[string]int{"true": 1, "false": 99},
+		Rules: []config.Rule{
+			{
+				ID: "staff",
+				When: config.WhenCondition{
+					All: []config.AttributeCondition{{Attr: "plan", Op: "eq", Value: "staff"}},
+				},
+				Then: config.ThenAction{Variants: map[string]int{"true": 50, "false": 50}},
+			},
+			beta,
+		},
+		Default: false,
+	})
+	for key, want := range assigned {
+		ctx := Context{Key: key, Attrs: map[string]any{"plan": "beta"}, Sticky: store}
+		if got, res := EvalBoolDetail(reordered, "test", ctx, false); got != want || res.RuleIndex != 1 {
+			t.Fatalf("%s: after inserting a rule = %v (%+v), want %v from rule 1", key, got, res, want)
+		}
+	}
+}
+
+func TestEval_Rollout(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	flag := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "bool",
+		Variants: map[string]int{"true": 0, "false": 100},
+		Rollout: &config.Rollout{
+			Variant: "true",
+			Start:   start,
+			End:     start.Add(24 * time.Hour),
+		},
+		Default: false,
+	})
+
+	// Contexts that have received the variant keep it as the ramp grows
+	enabled := make(map[string]bool)
+	for hour := 0; hour <= 25; hour++ {
+		now := start.Add(time.Duration(hour) * time.Hour)
+		clock := func() time.Time { return now }
+
+		count := 0
+		for i := 0; i < 1000; i++ {
+			ctx := Context{Key: "user:" + strconv.Itoa(i), Clock: clock}
+			on, reason := EvalBool(flag, "test", ctx, false)
+			if reason != Percent {
+				t.Fatalf("reason = %v, want Percent", reason)
+			}
+			if enabled[ctx.Key] && !on {
+				t.Fatalf("hour %d: %s left the rollout", hour, ctx.Key)
+			}
+			if on {
+				enabled[ctx.Key] = true
+				count++
+			}
+		}
+
+		if hour == 0 && count != 0 {
+			t.Errorf("hour 0: %d contexts enabled, want 0", count)
+		}
+		if hour == 12 && (count < 400 || count > 600) {
+			t.Errorf("hour 12: %d contexts enabled, want about 500", count)
+		}
+		if hour >= 24 && count != 1000 {
+			t.Errorf("hour %d: %d contexts enabled, want 1000", hour, count)
+		}
+	}
+}
+
+func TestEval_Schedule(t *testing.T) {
+	from := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	flag := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Variants: map[string]int{"regular": 100},
+		Schedule: config.Schedule{ActiveFrom: from, ActiveUntil: from.Add(96 * time.Hour)},
+		Rules: []config.Rule{
+			{
+				When: config.WhenCondition{
+					All: []config.AttributeCondition{{Attr: "plan", Op: "eq", Value: "pro"}},
+				},
+				Then: config.ThenAction{Variants: map[string]int{"weekend": 100}},
+				Schedule: config.Schedule{
+					Windows: []config.Window{{Days: []string{"sat", "sun"}, Start: "00:00", End: "24:00"}},
+				},
+			},
+		},
+		Default: "off",
+	})
+
+	at := func(t time.Time) func() time.Time { return func() time.Time { return t } }
+	pro := map[string]any{"plan": "pro"}
+
+	tests := []struct {
+		name    string
+		ctx     Context
+		want    string
+		wantRes Result
+	}{
+		{
+			name:    "before activation",
+			ctx:     Context{Key: "user:1", Attrs: pro, Clock: at(from.Add(-time.Hour))},
+			want:    "off",
+			wantRes: Result{Reason: Inactive, RuleIndex: -1},
+		},
+		{
+			name:    "rule window open",
+			ctx:     Context{Key: "user:1", Attrs: pro, Clock: at(from.Add(24 * time.Hour))}, // Saturday
+			want:    "weekend",
+			wantRes: Result{Variant: "weekend", Reason: Match, RuleIndex: 0},
+		},
+		{
+			name:    "rule window closed",
+			ctx:     Context{Key: "user:1", Attrs: pro, Clock: at(from.Add(72 * time.Hour))}, // Monday
+			want:    "regular",
+			wantRes: Result{Variant: "regular", Reason: Percent, RuleIndex: -1},
+		},
+		{
+			name:    "after activation",
+			ctx:     Context{Key: "user:1", Attrs: pro, Clock: at(from.Add(96 * time.Hour))},
+			want:    "off",
+			wantRes: Result{Reason: Inactive, RuleIndex: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, res := EvalStringDetail(flag, "test", tt.ctx, "caller")
+			if got != tt.want || res != tt.wantRes {
+				t.Errorf("EvalStringDetail() = %q, %+v, want %q, %+v", got, res, tt.want, tt.wantRes)
+			}
+		})
+	}
+}
+
+func TestEval_Prerequisites(t *testing.T) {
+	compiled, err := config.Compile(&config.Config{
+		Version: 1,
+		Flags: map[string]config.Flag{
+			"checkout": {
+				Enabled:  true,
+				Type:     "bool",
+				Variants: map[string]int{"true": 0, "false": 100},
+				Rules: []config.Rule{
+					{
+						When: config.WhenCondition{
+							All: []config.AttributeCondition{{Attr: "plan", Op: "eq", Value: "pro"}},
+						},
+						Then: config.ThenAction{Variants: map[string]int{"true": 100, "false": 0}},
+					},
+				},
+				Default: false,
+			},
+			"legacy": {
+				Enabled: false,
+				Type:    "string",
+				Default: "off",
+			},
+			"upsell": {
+				Enabled:       true,
+				Type:          "string",
+				Variants:      map[string]int{"banner": 100},
+				Prerequisites: []config.Prerequisite{{Flag: "checkout", Variant: "true"}},
+				Default:       "none",
+			},
+			"upsell_v2": {
+				Enabled:  true,
+				Type:     "string",
+				Variants: map[string]int{"modal": 100},
+				Prerequisites: []config.Prerequisite{
+					{Flag: "legacy", Variant: "off"}, // met through the disabled flag's default
+					{Flag: "upsell", Variant: "banner"},
+				},
+				Default: "none",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	pro := Context{Key: "user:1", Attrs: map[string]any{"plan": "pro"}}
+	free := Context{Key: "user:1", Attrs: map[string]any{"plan": "free"}}
+
+	tests := []struct {
+		name    string
+		flag    string
+		ctx     Context
+		want    string
+		wantRes Result
+	}{
+		{"met", "upsell", pro, "banner", Result{Variant: "banner", Reason: Percent, RuleIndex: -1}},
+		{"failed", "upsell", free, "none", Result{Reason: PrerequisiteFailed, RuleIndex: -1, Prerequisite: "checkout"}},
+		{"chain met", "upsell_v2", pro, "modal", Result{Variant: "modal", Reason: Percent, RuleIndex: -1}},
+		{"chain failed", "upsell_v2", free, "none", Result{Reason: PrerequisiteFailed, RuleIndex: -1, Prerequisite: "upsell"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, res := EvalStringDetail(compiled.Flags[tt.flag], tt.flag, tt.ctx, "caller")
+			if got != tt.want || res != tt.wantRes {
+				t.Errorf("EvalStringDetail() = %q, %+v, want %q, %+v", got, res, tt.want, tt.wantRes)
+			}
+		})
+	}
+}
+
+func TestEval_Targets(t *testing.T) {
+	flag := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Variants: map[string]int{"control": 100},
+		Targets: map[string][]string{
+			"treatment": {"user:1", "user:2"},
+			"control":   {"user:3"},
+		},
+		Rules: []config.Rule{
+			{
+				When: config.WhenCondition{
+					All: []config.AttributeCondition{{Attr: "plan", Op: "eq", Value: "pro"}},
+				},
+				Then: config.ThenAction{Variants: map[string]int{"pro": 100}},
+			},
+		},
+		Default: "control",
+	})
+
+	pro := map[string]any{"plan": "pro"}
+	tests := []struct {
+		name    string
+		ctx     Context
+		want    string
+		wantRes Result
+	}{
+		{"targeted before rules", Context{Key: "user:1", Attrs: pro}, "treatment", Result{Variant: "treatment", Reason: TargetMatch, RuleIndex: -1}},
+		{"targeted control", Context{Key: "user:3", Attrs: pro}, "control", Result{Variant: "control", Reason: TargetMatch, RuleIndex: -1}},
+		{"not targeted", Context{Key: "user:4", Attrs: pro}, "pro", Result{Variant: "pro", Reason: Match, RuleIndex: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, res := EvalStringDetail(flag, "test", tt.ctx, "")
+			if got != tt.want || res != tt.wantRes {
+				t.Errorf("EvalStringDetail() = %q, %+v, want %q, %+v", got, res, tt.want, tt.wantRes)
+			}
+		})
+	}
+}
+
+func TestEval_ZeroAllocs(t *testing.T) {
+	boolFlag := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "bool",
+		Variants: map[string]int{"true": 50, "false": 50},
+		Rules: []config.Rule{
+			{
+				When: config.WhenCondition{
+					All: []config.AttributeCondition{{Attr: "plan", Op: "eq", Value: "pro"}},
+				},
+				Then: config.ThenAction{Variants: map[string]int{"true": 90, "false": 10}},
+			},
+		},
+	})
+	stringFlag := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "string",
+		Variants: map[string]int{"red": 40, "blue": 30, "green": 30},
+	})
+
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"plan": "pro"}}
+
+	if n := testing.AllocsPerRun(100, func() { EvalBool(boolFlag, "test_flag", ctx, false) }); n != 0 {
+		t.Errorf("EvalBool() allocs = %v, want 0", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { EvalString(stringFlag, "test_flag", ctx, "") }); n != 0 {
+		t.Errorf("EvalString() allocs = %v, want 0", n)
+	}
+
+	targeted := compileFlag(t, config.Flag{
+		Enabled:  true,
+		Type:     "bool",
+		Variants: map[string]int{"true": 50, "false": 50},
+		Targets:  map[string][]string{"true": {"user:123"}},
+	})
+	if n := testing.AllocsPerRun(100, func() { EvalBool(targeted, "test_flag", ctx, false) }); n != 0 {
+		t.Errorf("EvalBool() with targets allocs = %v, want 0", n)
+	}
+
+	ruleHeavy := ruleHeavyFlag(t)
+	heavyCtx := Context{
+		Key:   "user:123",
+		Attrs: map[string]any{"plan": "pro", "seats": 40, "country": "c19", "email": "jane@example.com"},
+	}
+	if n := testing.AllocsPerRun(100, func() { EvalBool(ruleHeavy, "test_flag", heavyCtx, false) }); n != 0 {
+		t.Errorf("EvalBool() with many rules allocs = %v, want 0", n)
+	}
+
+	// Flags built without Compile resolve their distribution and operands once
+	handBuilt := &config.CompiledFlag{
+		Enabled:  true,
+		Type:     "string",
+		Variants: map[string]int{"red": 40, "blue": 30, "green": 30},
+		Rules: []*config.CompiledRule{
+			{
+				Conditions: []*config.CompiledCondition{
+					{Attr: "plan", Op: "in", Value: []any{"pro", "enterprise"}, IsAll: true},
+				},
+				Variants: map[string]int{"red": 50, "blue": 50},
+			},
+		},
+	}
+	EvalString(handBuilt, "test_flag", ctx, "")
+	if n := testing.AllocsPerRun(100, func() { EvalString(handBuilt, "test_flag", ctx, "") }); n != 0 {
+		t.Errorf("EvalString() without Compile allocs = %v, want 0", n)
+	}
+}
@@ -2,144 +2,303 @@ package eval
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
 	"regexp"
-	"strconv"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/0mjs/goff/internal/cidr"
+	"github.com/0mjs/goff/internal/config"
+	"github.com/0mjs/goff/internal/semver"
 )
 
-// EvalOperator evaluates an attribute condition.
+// operatorFunc evaluates an attribute value against an operand compiled by
+// config.CompileOperand.
+type operatorFunc func(attrValue, opValue any, regex *regexp.Regexp) (bool, error)
+
+// operators is the dispatch table, indexed by opcode.
+var operators = [config.NumOpCodes]operatorFunc{
+	config.OpEq:            opEq,
+	config.OpNeq:           not(opEq),
+	config.OpGt:            compare(func(c int) bool { return c > 0 }),
+	config.OpGte:           compare(func(c int) bool { return c >= 0 }),
+	config.OpLt:            compare(func(c int) bool { return c < 0 }),
+	config.OpLte:           compare(func(c int) bool { return c <= 0 }),
+	config.OpIn:            opIn,
+	config.OpContains:      stringOp(strings.Contains),
+	config.OpMatches:       opMatches,
+	config.OpStartsWith:    stringOp(strings.HasPrefix),
+	config.OpEndsWith:      stringOp(strings.HasSuffix),
+	config.OpNotIn:         not(opIn),
+	config.OpNotContains:   not(stringOp(strings.Contains)),
+	config.OpExists:        opExists,
+	config.OpNotExists:     not(opExists),
+	config.OpEqCI:          opEqCI,
+	config.OpInCI:          opIn,
+	config.OpSemverEq:      semverCompare(func(c int) bool { return c == 0 }),
+	config.OpSemverGt:      semverCompare(func(c int) bool { return c > 0 }),
+	config.OpSemverGte:     semverCompare(func(c int) bool { return c >= 0 }),
+	config.OpSemverLt:      semverCompare(func(c int) bool { return c < 0 }),
+	config.OpSemverLte:     semverCompare(func(c int) bool { return c <= 0 }),
+	config.OpSemverInRange: opSemverInRange,
+	config.OpBefore:        timeOp(config.OpBefore),
+	config.OpAfter:         timeOp(config.OpAfter),
+	config.OpIPInCIDR:      opIPInCIDR,
+	config.OpAnyOf:         setOp(func(shared, size int) bool { return shared > 0 }),
+	config.OpAllOf:         setOp(func(shared, size int) bool { return shared == size }),
+	config.OpNoneOf:        setOp(func(shared, size int) bool { return shared == 0 }),
+}
+
+// matchesMissing lists the operators that match when the attribute is not
+// set at all: a missing attribute does not exist and shares no values with a
+// list. Like every other operator, neq, not_in and not_contains need the
+// attribute to be present.
+var matchesMissing = [config.NumOpCodes]bool{
+	config.OpNotExists: true,
+	config.OpNoneOf:    true,
+}
+
+// EvalOperator evaluates an attribute condition, compiling opValue first.
 // Returns (match, error). If error is non-nil, the condition should be skipped.
 func EvalOperator(attrValue any, op string, opValue any, compiledRegex *regexp.Regexp) (bool, error) {
-	switch op {
-	case "eq":
-		return eq(attrValue, opValue)
-	case "neq":
-		match, err := eq(attrValue, opValue)
-		return !match, err
-	case "gt":
-		return compare(attrValue, opValue, 1)
-	case "gte":
-		return compare(attrValue, opValue, 0, 1)
-	case "lt":
-		return compare(attrValue, opValue, -1)
-	case "lte":
-		return compare(attrValue, opValue, -1, 0)
-	case "in":
-		return in(attrValue, opValue)
-	case "contains":
-		return contains(attrValue, opValue)
-	case "matches":
-		return matches(attrValue, compiledRegex)
-	default:
+	code, ok := config.LookupOp(op)
+	if !ok {
 		return false, fmt.Errorf("unknown operator: %s", op)
 	}
+	operand, err := config.CompileOperand(op, opValue)
+	if err != nil {
+		return false, err
+	}
+	return operators[code](attrValue, operand, compiledRegex)
 }
 
-func eq(attrValue, opValue any) (bool, error) {
-	// Direct comparison first
-	if attrValue == opValue {
-		return true, nil
+// not negates an operator. Errors still mean no match.
+func not(f operatorFunc) operatorFunc {
+	return func(attrValue, opValue any, regex *regexp.Regexp) (bool, error) {
+		match, err := f(attrValue, opValue, regex)
+		return !match && err == nil, err
 	}
+}
 
-	// Try numeric comparison
-	attrNum, attrOk := toFloat64(attrValue)
-	opNum, opOk := toFloat64(opValue)
-	if attrOk && opOk {
-		return attrNum == opNum, nil
+func operand(opValue any) (*config.Operand, error) {
+	o, ok := opValue.(*config.Operand)
+	if !ok {
+		return nil, fmt.Errorf("operand not compiled: %T", opValue)
 	}
-
-	// Try string comparison
-	attrStr := fmt.Sprintf("%v", attrValue)
-	opStr := fmt.Sprintf("%v", opValue)
-	return attrStr == opStr, nil
+	return o, nil
 }
 
-func compare(attrValue, opValue any, allowedResults ...int) (bool, error) {
-	attrNum, attrOk := toFloat64(attrValue)
-	opNum, opOk := toFloat64(opValue)
+// opEq compares numerically when both sides are numbers or numeric strings,
+// and by string form otherwise.
+func opEq(attrValue, opValue any, _ *regexp.Regexp) (bool, error) {
+	o, err := operand(opValue)
+	if err != nil {
+		return false, err
+	}
 
-	if !attrOk || !opOk {
-		return false, fmt.Errorf("comparison operators require numeric values")
+	// Identical strings are equal even if numerically they are not, as "NaN"
+	if s, ok := attrValue.(string); ok && s == o.Str {
+		if _, ok := o.Value.(string); ok {
+			return true, nil
+		}
 	}
 
-	result := 0
-	if attrNum > opNum {
-		result = 1
-	} else if attrNum < opNum {
-		result = -1
+	if o.IsNum {
+		if attrNum, ok := config.ParseNumber(attrValue); ok {
+			result, ordered := config.CompareNumbers(attrNum, o.Num)
+			return ordered && result == 0, nil
+		}
 	}
+	return config.FormatValue(attrValue) == o.Str, nil
+}
 
-	for _, allowed := range allowedResults {
-		if result == allowed {
-			return true, nil
+func opEqCI(attrValue, opValue any, regex *regexp.Regexp) (bool, error) {
+	if match, err := opEq(attrValue, opValue, regex); match || err != nil {
+		return match, err
+	}
+	o, _ := operand(opValue)
+	return strings.EqualFold(config.FormatValue(attrValue), o.Str), nil
+}
+
+func compare(accept func(int) bool) operatorFunc {
+	return func(attrValue, opValue any, _ *regexp.Regexp) (bool, error) {
+		o, err := operand(opValue)
+		if err != nil {
+			return false, err
+		}
+		attrNum, ok := config.ParseNumber(attrValue)
+		if !ok || !o.IsNum {
+			return false, fmt.Errorf("comparison operators require numeric values")
+		}
+
+		// Nothing is greater than, less than or equal to NaN
+		result, ordered := config.CompareNumbers(attrNum, o.Num)
+		return ordered && accept(result), nil
+	}
+}
+
+// stringOp applies a string predicate to the string forms of both sides.
+func stringOp(f func(s, operand string) bool) operatorFunc {
+	return func(attrValue, opValue any, _ *regexp.Regexp) (bool, error) {
+		o, err := operand(opValue)
+		if err != nil {
+			return false, err
 		}
+		return f(config.FormatValue(attrValue), o.Str), nil
 	}
-	return false, nil
 }
 
-func in(attrValue, opValue any) (bool, error) {
-	// opValue should be a slice/array
-	opSlice, ok := opValue.([]any)
+func opIn(attrValue, opValue any, _ *regexp.Regexp) (bool, error) {
+	set, ok := opValue.(*config.StringSet)
 	if !ok {
 		return false, fmt.Errorf("'in' operator requires array value")
 	}
+	return set.Has(config.FormatValue(attrValue)), nil
+}
 
-	attrStr := fmt.Sprintf("%v", attrValue)
-	for _, v := range opSlice {
-		if fmt.Sprintf("%v", v) == attrStr {
-			return true, nil
+func opMatches(attrValue, _ any, regex *regexp.Regexp) (bool, error) {
+	if regex == nil {
+		return false, fmt.Errorf("regex not compiled")
+	}
+	return regex.MatchString(config.FormatValue(attrValue)), nil
+}
+
+func opExists(attrValue, _ any, _ *regexp.Regexp) (bool, error) {
+	return attrValue != nil, nil
+}
+
+func semverCompare(accept func(int) bool) operatorFunc {
+	return func(attrValue, opValue any, _ *regexp.Regexp) (bool, error) {
+		attrVer, err := toVersion(attrValue)
+		if err != nil {
+			return false, err
+		}
+		opVer, ok := opValue.(semver.Version)
+		if !ok {
+			return false, fmt.Errorf("operand not compiled: %T", opValue)
 		}
+		return accept(semver.Compare(attrVer, opVer)), nil
 	}
-	return false, nil
 }
 
-func contains(attrValue, opValue any) (bool, error) {
-	attrStr := fmt.Sprintf("%v", attrValue)
-	opStr := fmt.Sprintf("%v", opValue)
-	return strings.Contains(attrStr, opStr), nil
+func opSemverInRange(attrValue, opValue any, _ *regexp.Regexp) (bool, error) {
+	attrVer, err := toVersion(attrValue)
+	if err != nil {
+		return false, err
+	}
+	constraint, ok := opValue.(semver.Constraint)
+	if !ok {
+		return false, fmt.Errorf("'semver_in_range' operator requires a constraint")
+	}
+	return constraint.Check(attrVer), nil
 }
 
-func matches(attrValue any, regex *regexp.Regexp) (bool, error) {
-	if regex == nil {
-		return false, fmt.Errorf("regex not compiled")
+// toVersion accepts a parsed version or a version string.
+func toVersion(v any) (semver.Version, error) {
+	switch s := v.(type) {
+	case semver.Version:
+		return s, nil
+	case string:
+		return semver.Parse(s)
+	}
+	return semver.Version{}, fmt.Errorf("semver operators require version strings, got %T", v)
+}
+
+// timeOp evaluates before or after. Relative operands are resolved against
+// time.Now; evalCondition resolves them against the context's clock instead.
+func timeOp(code config.OpCode) operatorFunc {
+	return func(attrValue, opValue any, _ *regexp.Regexp) (bool, error) {
+		operand, ok := opValue.(config.TimeOperand)
+		if !ok {
+			return false, fmt.Errorf("operand not compiled: %T", opValue)
+		}
+		return compareTime(attrValue, code, operand.At(time.Now()))
+	}
+}
+
+// compareTime evaluates before or after against the instant at.
+func compareTime(attrValue any, code config.OpCode, at time.Time) (bool, error) {
+	t, ok := config.ParseInstant(attrValue)
+	if !ok {
+		return false, fmt.Errorf("time operators require a time, got %T", attrValue)
+	}
+	if code == config.OpBefore {
+		return t.Before(at), nil
 	}
-	attrStr := fmt.Sprintf("%v", attrValue)
-	return regex.MatchString(attrStr), nil
-}
-
-func toFloat64(v any) (float64, bool) {
-	switch n := v.(type) {
-	case float64:
-		return n, true
-	case float32:
-		return float64(n), true
-	case int:
-		return float64(n), true
-	case int8:
-		return float64(n), true
-	case int16:
-		return float64(n), true
-	case int32:
-		return float64(n), true
-	case int64:
-		return float64(n), true
-	case uint:
-		return float64(n), true
-	case uint8:
-		return float64(n), true
-	case uint16:
-		return float64(n), true
-	case uint32:
-		return float64(n), true
-	case uint64:
-		return float64(n), true
+	return t.After(at), nil
+}
+
+func opIPInCIDR(attrValue, opValue any, _ *regexp.Regexp) (bool, error) {
+	set, ok := opValue.(*cidr.Set)
+	if !ok {
+		return false, fmt.Errorf("operand not compiled: %T", opValue)
+	}
+	addr, ok := toAddr(attrValue)
+	if !ok {
+		return false, fmt.Errorf("'ip_in_cidr' operator requires an IP address, got %v", attrValue)
+	}
+	return set.Contains(addr), nil
+}
+
+// toAddr accepts a netip.Addr, a net.IP or an address string.
+func toAddr(v any) (netip.Addr, bool) {
+	switch a := v.(type) {
+	case netip.Addr:
+		return a, a.IsValid()
+	case net.IP:
+		return netip.AddrFromSlice(a)
 	case string:
-		f, err := strconv.ParseFloat(n, 64)
-		if err != nil {
-			return 0, false
+		addr, err := netip.ParseAddr(a)
+		return addr, err == nil
+	}
+	return netip.Addr{}, false
+}
+
+// setOp intersects a list attribute with a set: any_of matches if they
+// share an element, all_of if every set value is in the list, none_of if
+// they share none. An attribute that is not a list is a list of one.
+func setOp(accept func(shared, size int) bool) operatorFunc {
+	return func(attrValue, opValue any, _ *regexp.Regexp) (bool, error) {
+		set, ok := opValue.(*config.ValueSet)
+		if !ok {
+			return false, fmt.Errorf("operand not compiled: %T", opValue)
+		}
+
+		var shared int
+		switch attr := attrValue.(type) {
+		case []string:
+			shared = countShared(set, attr)
+		case []int:
+			shared = countShared(set, attr)
+		case []int64:
+			shared = countShared(set, attr)
+		case []float64:
+			shared = countShared(set, attr)
+		case []any:
+			// Elements of different types can be the same set value, 1 and 1.0
+			for i, v := range attr {
+				if set.Has(v) && !slices.ContainsFunc(attr[:i], func(prev any) bool { return config.SameValue(prev, v) }) {
+					shared++
+				}
+			}
+		default:
+			if set.Has(attrValue) {
+				shared = 1
+			}
+		}
+		return accept(shared, set.Len()), nil
+	}
+}
+
+// countShared returns the number of distinct set values in attr.
+func countShared[T comparable](set *config.ValueSet, attr []T) int {
+	shared := 0
+	for i, v := range attr {
+		if set.Has(v) && !slices.Contains(attr[:i], v) {
+			shared++
 		}
-		return f, true
-	default:
-		return 0, false
 	}
+	return shared
 }
@@ -1,8 +1,16 @@
 package eval
 
 import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/netip"
 	"regexp"
 	"testing"
+	"time"
+
+	"github.com/0mjs/goff/internal/config"
+	"github.com/0mjs/goff/internal/semver"
 )
 
 func TestEvalOperator_eq(t *testing.T) {
@@ -212,3 +220,252 @@ func TestEvalOperator_UnknownOp(t *testing.T) {
 		t.Error("EvalOperator() expected error for unknown operator")
 	}
 }
+
+func TestEvalOperator_semver(t *testing.T) {
+	tests := []struct {
+		name    string
+		attr    any
+		op      string
+		opValue any
+		want    bool
+		wantErr bool
+	}{
+		{"minor compares numerically", "1.10.0", "semver_gt", "1.9.2", true, false},
+		{"equal", "v2.0.0", "semver_eq", "2.0.0", true, false},
+		{"pre-release is lower", "2.0.0-rc.1", "semver_lt", "2.0.0", true, false},
+		{"gte equal", "2.3.0", "semver_gte", "2.3", true, false},
+		{"lte higher", "2.3.1", "semver_lte", "2.3.0", false, false},
+		{"precompiled", "1.10.0", "semver_gt", mustVersion(t, "1.9.2"), true, false},
+		{"in range", "2.10.1", "semver_in_range", ">=2.3.0 <3", true, false},
+		{"out of range", "3.0.0", "semver_in_range", mustConstraint(t, ">=2.3.0 <3"), false, false},
+		{"invalid attribute", "latest", "semver_gt", "1.0.0", false, true},
+		{"non-string attribute", 1.1, "semver_gt", "1.0.0", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalOperator(tt.attr, tt.op, tt.opValue, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EvalOperator() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EvalOperator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustVersion(t *testing.T, s string) semver.Version {
+	t.Helper()
+	v, err := semver.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func mustConstraint(t *testing.T, s string) semver.Constraint {
+	t.Helper()
+	c, err := semver.ParseConstraint(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEvalOperator_time(t *testing.T) {
+	cutoff := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		attr    any
+		op      string
+		opValue any
+		want    bool
+		wantErr bool
+	}{
+		{"rfc3339 after", "2026-02-01T00:00:00Z", "after", "2026-01-01", true, false},
+		{"rfc3339 before", "2025-12-31T23:59:59Z", "before", cutoff, true, false},
+		{"time.Time", cutoff.Add(time.Hour), "after", cutoff, true, false},
+		{"equal is neither", cutoff, "after", cutoff, false, false},
+		{"unix seconds", 1767225599, "before", cutoff, true, false},
+		{"unix millis", float64(1767225601000), "after", cutoff, true, false},
+		{"compiled operand", "2026-02-01", "after", config.TimeOperand{Time: cutoff}, true, false},
+		{"relative literal", "2000-01-01", "before", "now-30d", true, false},
+		{"invalid attribute", "soon", "after", cutoff, false, true},
+		{"invalid operand", cutoff, "after", "soon", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalOperator(tt.attr, tt.op, tt.opValue, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EvalOperator() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EvalOperator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalOperator_strings(t *testing.T) {
+	tests := []struct {
+		name    string
+		attr    any
+		op      string
+		opValue any
+		want    bool
+		wantErr bool
+	}{
+		{"starts_with", "user@example.com", "starts_with", "user@", true, false},
+		{"starts_with mismatch", "admin@example.com", "starts_with", "user@", false, false},
+		{"ends_with", "user@example.com", "ends_with", "@example.com", true, false},
+		{"ends_with mismatch", "user@example.org", "ends_with", "@example.com", false, false},
+		{"not_in", "eu", "not_in", []any{"us", "ca"}, true, false},
+		{"not_in member", "us", "not_in", []any{"us", "ca"}, false, false},
+		{"not_in requires array", "us", "not_in", "us", false, true},
+		{"not_contains", "hello world", "not_contains", "bye", true, false},
+		{"not_contains substring", "hello world", "not_contains", "world", false, false},
+		{"exists", "", "exists", nil, true, false},
+		{"exists nil", nil, "exists", nil, false, false},
+		{"not_exists nil", nil, "not_exists", nil, true, false},
+		{"eq_ci", "Pro", "eq_ci", "pRO", true, false},
+		{"eq_ci mismatch", "pro", "eq_ci", "basic", false, false},
+		{"in_ci", "US", "in_ci", []any{"us", "ca"}, true, false},
+		{"in_ci mismatch", "EU", "in_ci", []any{"us", "ca"}, false, false},
+		{"in_ci requires array", "us", "in_ci", "us", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalOperator(tt.attr, tt.op, tt.opValue, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EvalOperator() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EvalOperator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalOperator_ipInCIDR(t *testing.T) {
+	offices, err := config.ParseCIDRs([]any{"10.0.0.0/8", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("ParseCIDRs() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		attr    any
+		opValue any
+		want    bool
+		wantErr bool
+	}{
+		{"ipv4 string", "10.20.30.40", offices, true, false},
+		{"ipv4 outside", "192.168.0.1", offices, false, false},
+		{"ipv6 string", "2001:db8::42", offices, true, false},
+		{"net.IP", net.ParseIP("10.0.0.1"), offices, true, false},
+		{"netip.Addr", netip.MustParseAddr("2001:db9::1"), offices, false, false},
+		{"single cidr literal", "192.168.1.5", "192.168.1.0/24", true, false},
+		{"list literal", "192.168.1.5", []any{"172.16.0.0/12", "192.168.0.0/16"}, true, false},
+		{"invalid address", "not-an-ip", offices, false, true},
+		{"invalid cidr", "10.0.0.1", "10.0.0.0/40", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalOperator(tt.attr, "ip_in_cidr", tt.opValue, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EvalOperator() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EvalOperator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalOperator_sets(t *testing.T) {
+	roles, err := config.NewValueSet([]any{"admin", "billing"})
+	if err != nil {
+		t.Fatalf("NewValueSet() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		attr    any
+		op      string
+		opValue any
+		want    bool
+		wantErr bool
+	}{
+		{"any_of []string", []string{"viewer", "billing"}, "any_of", roles, true, false},
+		{"any_of none shared", []string{"viewer"}, "any_of", roles, false, false},
+		{"any_of []any", []any{"admin", 3}, "any_of", roles, true, false},
+		{"any_of []int", []int{3, 7}, "any_of", []any{7, 8}, true, false},
+		{"any_of scalar", "admin", "any_of", roles, true, false},
+		{"any_of empty list", []string{}, "any_of", roles, false, false},
+		{"all_of", []string{"billing", "viewer", "admin"}, "all_of", roles, true, false},
+		{"all_of missing one", []string{"admin", "admin"}, "all_of", roles, false, false},
+		{"all_of mixed numbers", []any{1, 1.0}, "all_of", []any{1, 2}, false, false},
+		{"all_of empty set", []string{"viewer"}, "all_of", []any{}, true, false},
+		{"none_of", []string{"viewer"}, "none_of", roles, true, false},
+		{"none_of shared", []any{"viewer", "admin"}, "none_of", roles, false, false},
+		{"types are not coerced", []string{"1"}, "any_of", []any{1}, false, false},
+		{"requires array", []string{"admin"}, "any_of", "admin", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalOperator(tt.attr, tt.op, tt.opValue, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EvalOperator() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EvalOperator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalOperator_largeIntegers(t *testing.T) {
+	tests := []struct {
+		name    string
+		attr    any
+		op      string
+		opValue any
+		want    bool
+	}{
+		{"ids above 2^53 differ", int64(9007199254740993), "eq", int64(9007199254740992), false},
+		{"ids above 2^53 equal", int64(9007199254740993), "eq", 9007199254740993, true},
+		{"uint64 id", uint64(18446744073709551615), "eq", uint64(18446744073709551614), false},
+		{"uint64 gt", uint64(18446744073709551615), "gt", uint64(18446744073709551614), true},
+		{"int vs float", int64(9007199254740993), "gt", float64(9007199254740992), true},
+		{"int vs fraction", 2, "lt", 2.5, true},
+		{"json.Number", json.Number("9007199254740993"), "eq", int64(9007199254740993), true},
+		{"json.Number differs", json.Number("9007199254740993"), "neq", int64(9007199254740992), true},
+		{"json.Number float", json.Number("2.5"), "gte", 2.5, true},
+		{"numeric string", "9007199254740993", "lte", int64(9007199254740992), false},
+		{"NaN never orders", math.NaN(), "gte", 1, false},
+		{"any_of large ids", []int64{9007199254740993}, "any_of", []any{int64(9007199254740992)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalOperator(tt.attr, tt.op, tt.opValue, nil)
+			if err != nil {
+				t.Fatalf("EvalOperator() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EvalOperator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package eval
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/0mjs/goff/internal/config"
+)
+
+// lookupAttr returns the value a condition refers to. An attribute whose
+// name is present verbatim wins; otherwise a path such as "org.plan" is
+// followed through nested maps, slices and structs. A path with a missing
+// key, an out-of-range index, a nil value or a value of the wrong kind along
+// the way is treated like a missing attribute.
+func lookupAttr(attrs map[string]any, cond *config.CompiledCondition) (any, bool) {
+	if v, ok := attrs[cond.Attr]; ok || cond.Path == nil {
+		return v, ok
+	}
+
+	var current any = attrs
+	for _, seg := range cond.Path {
+		var ok bool
+		if seg.IsIndex {
+			current, ok = index(current, seg.Index)
+		} else {
+			current, ok = field(current, seg.Key)
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// field returns the map entry or struct field named key.
+func field(v any, key string) (any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		child, ok := m[key]
+		return child, ok
+	case nil:
+		return nil, false
+	}
+
+	rv := indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		child := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !child.IsValid() {
+			return nil, false
+		}
+		return child.Interface(), true
+	case reflect.Struct:
+		if f, ok := structField(rv, key); ok {
+			return f.Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// structField finds an exported field by its json tag name or, failing
+// that, by case-insensitive field name.
+func structField(rv reflect.Value, key string) (reflect.Value, bool) {
+	t := rv.Type()
+	match := -1
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name == key {
+			return rv.Field(i), true
+		}
+		if match < 0 && strings.EqualFold(f.Name, key) {
+			match = i
+		}
+	}
+	if match < 0 {
+		return reflect.Value{}, false
+	}
+	return rv.Field(match), true
+}
+
+// index returns element i of a slice or array.
+func index(v any, i int) (any, bool) {
+	switch s := v.(type) {
+	case []any:
+		if i >= len(s) {
+			return nil, false
+		}
+		return s[i], true
+	case nil:
+		return nil, false
+	}
+
+	rv := indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if i >= rv.Len() {
+			return nil, false
+		}
+		return rv.Index(i).Interface(), true
+	}
+	return nil, false
+}
+
+// indirect follows pointers and interfaces. A nil pointer yields the zero
+// Value, whose Kind matches nothing.
+func indirect(rv reflect.Value) reflect.Value {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
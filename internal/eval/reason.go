@@ -4,11 +4,16 @@ package eval
 type Reason uint8
 
 const (
-	Match Reason = iota
-	Percent
-	Default
-	Disabled
-	Missing
-	Error
+	Match                Reason = iota // a targeting rule matched
+	Percent                            // no rule matched; the fallthrough percentage split decided
+	Default                            // no variants apply; the flag default was used
+	Disabled                           // the flag is disabled
+	Missing                            // the flag does not exist
+	Error                              // evaluation failed; the caller default was used
+	TargetMatch                        // the context key was individually targeted
+	TypeMismatch                       // the flag was evaluated as a different type
+	MissingBucketKey                   // the attribute a split buckets by is absent; the flag default was used
+	Inactive                           // the flag is outside its activation schedule; the flag default was used
+	PrerequisiteFailed                 // a prerequisite flag did not produce its required variant; the flag default was used
+	BucketKeyFallthrough               // a matching rule's bucket_by attribute was absent; the flag's split or default decided
 )
-
@@ -1,68 +1,123 @@
 package eval
 
 import (
+	"time"
+
 	"github.com/0mjs/goff/internal/config"
 )
 
 // Context represents the evaluation context.
 type Context struct {
-	Key   string
-	Attrs map[string]any
+	Key    string
+	Attrs  map[string]any
+	Sticky StickyStore      // consulted by splits of sticky flags; may be nil
+	Clock  func() time.Time // current time for rollouts, schedules and time conditions; nil means time.Now
+}
+
+// now returns the evaluation time.
+func (c Context) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock()
+	}
+	return time.Now()
 }
 
 // EvalRule evaluates a compiled rule against a context.
 // Returns true if the rule matches, false otherwise.
 // Errors are logged but don't prevent evaluation (rule is skipped).
 func EvalRule(rule *config.CompiledRule, ctx Context) bool {
-	if len(rule.Conditions) == 0 {
+	if rule.Segment != nil && !EvalSegment(rule.Segment, ctx) {
 		return false
 	}
 
-	// Check if all conditions match (for "all") or any condition matches (for "any")
-	allMatch := true
-	anyMatch := false
+	switch {
+	case rule.When != nil:
+		return evalNode(rule.When, ctx)
+	case len(rule.Conditions) > 0:
+		// Flat rules constructed without config.Compile
+		return evalConditions(rule.Conditions, ctx)
+	case rule.Expr != nil:
+		// An expression that fails to evaluate does not match
+		match, err := rule.Expr.Eval(ctx.Attrs)
+		return err == nil && match
+	}
+	return rule.Segment != nil
+}
 
-	for _, cond := range rule.Conditions {
-		attrValue, exists := ctx.Attrs[cond.Attr]
-		if !exists {
-			// Attribute missing - condition fails
-			if cond.IsAll {
-				allMatch = false
-				break
-			}
-			continue
-		}
+// EvalSegment reports whether the context belongs to the segment.
+func EvalSegment(segment *config.CompiledSegment, ctx Context) bool {
+	if _, ok := segment.Included[ctx.Key]; ok {
+		return true
+	}
+	if _, ok := segment.Excluded[ctx.Key]; ok {
+		return false
+	}
+	return segment.When != nil && evalNode(segment.When, ctx)
+}
 
-		match, err := EvalOperator(attrValue, cond.Op, cond.Value, cond.Regex)
-		if err != nil {
-			// Evaluation error - skip this condition
-			// For "all", this means the rule doesn't match
-			// For "any", we continue checking other conditions
-			if cond.IsAll {
-				allMatch = false
-				break
+// evalNode evaluates a condition tree, stopping at the first child that
+// decides a group.
+func evalNode(node *config.CompiledNode, ctx Context) bool {
+	switch {
+	case node.Condition != nil:
+		return evalCondition(node.Condition, ctx)
+	case node.Not != nil:
+		return !evalNode(node.Not, ctx)
+	case len(node.All) > 0:
+		for _, child := range node.All {
+			if !evalNode(child, ctx) {
+				return false
 			}
-			continue
 		}
-
-		if match {
-			anyMatch = true
-			if !cond.IsAll {
-				// For "any", first match wins
-				break
-			}
-		} else {
-			if cond.IsAll {
-				allMatch = false
-				break
-			}
+		return true
+	}
+	for _, child := range node.Any {
+		if evalNode(child, ctx) {
+			return true
 		}
 	}
+	return false
+}
+
+// evalConditions evaluates a flat all or any condition list. An empty list
+// does not match.
+func evalConditions(conditions []*config.CompiledCondition, ctx Context) bool {
+	if len(conditions) == 0 {
+		return false
+	}
 
 	// If IsAll is true, all conditions must match
 	// If IsAll is false, any condition must match
-	if rule.Conditions[0].IsAll {
-		return allMatch
+	isAll := conditions[0].IsAll
+	for _, cond := range conditions {
+		if evalCondition(cond, ctx) != isAll {
+			// For "all", first mismatch fails; for "any", first match wins
+			return !isAll
+		}
 	}
-	return anyMatch
+	return isAll
+}
+
+// evalCondition evaluates a single condition. A missing attribute matches
+// only not_exists and none_of; an operator error means the condition does not
+// match.
+func evalCondition(cond *config.CompiledCondition, ctx Context) bool {
+	code, value, err := cond.Operand()
+
+	attrValue, exists := lookupAttr(ctx.Attrs, cond)
+	if !exists {
+		return matchesMissing[code]
+	}
+	if err != nil {
+		return false
+	}
+
+	// Relative times are resolved against the context's clock
+	if operand, ok := value.(config.TimeOperand); ok {
+		match, err := compareTime(attrValue, code, operand.At(ctx.now()))
+		return err == nil && match
+	}
+
+	match, err := operators[code](attrValue, value, cond.Regex)
+	return err == nil && match
 }
@@ -28,3 +28,48 @@ func FuzzEvalRule(f *testing.F) {
 		_ = EvalRule(rule, ctx)
 	})
 }
+
+func FuzzEvalOperator_Strings(f *testing.F) {
+	f.Add("user@example.com", "user@")
+	f.Add("Pro", "pRO")
+	f.Add("", "")
+	f.Add("straße", "STRASSE")
+	f.Add("1", "1.0")
+	f.Fuzz(func(t *testing.T, attr, value string) {
+		eval := func(op string, opValue any) bool {
+			match, err := EvalOperator(attr, op, opValue, nil)
+			if err != nil {
+				t.Fatalf("EvalOperator(%q, %s, %q) error = %v", attr, op, opValue, err)
+			}
+			return match
+		}
+
+		// Negated operators are the complements of their counterparts
+		if eval("neq", value) == eval("eq", value) {
+			t.Errorf("neq and eq agree for %q, %q", attr, value)
+		}
+		list := []any{value}
+		if eval("not_in", list) == eval("in", list) {
+			t.Errorf("not_in and in agree for %q, %q", attr, value)
+		}
+		if eval("not_contains", value) == eval("contains", value) {
+			t.Errorf("not_contains and contains agree for %q, %q", attr, value)
+		}
+
+		// Prefixes and suffixes are substrings
+		if (eval("starts_with", value) || eval("ends_with", value)) && !eval("contains", value) {
+			t.Errorf("starts_with or ends_with without contains for %q, %q", attr, value)
+		}
+
+		// Case-insensitive operators accept whatever their exact forms do
+		if eval("eq", value) && !eval("eq_ci", value) {
+			t.Errorf("eq without eq_ci for %q, %q", attr, value)
+		}
+		if eval("in", list) && !eval("in_ci", list) {
+			t.Errorf("in without in_ci for %q, %q", attr, value)
+		}
+		if eval("in_ci", list) && !eval("eq_ci", value) {
+			t.Errorf("in_ci without eq_ci for %q, %q", attr, value)
+		}
+	})
+}
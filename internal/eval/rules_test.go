@@ -2,6 +2,7 @@ package eval
 
 import (
 	"testing"
+	"time"
 
 	"github.com/0mjs/goff/internal/config"
 )
@@ -97,3 +98,288 @@ func TestEvalRule_ErrorHandling(t *testing.T) {
 		t.Error("EvalRule() should return false when operator evaluation fails")
 	}
 }
+
+func TestEvalRule_Segment(t *testing.T) {
+	staff := &config.CompiledSegment{
+		Name: "internal_staff",
+		When: &config.CompiledNode{
+			Condition: &config.CompiledCondition{Attr: "email", Op: "contains", Value: "@ourco.com"},
+		},
+		Included: map[string]struct{}{"user:contractor": {}},
+		Excluded: map[string]struct{}{"user:intern": {}},
+	}
+	segmentOnly := &config.CompiledRule{Segment: staff}
+	segmentAndCondition := &config.CompiledRule{
+		Segment: staff,
+		Conditions: []*config.CompiledCondition{
+			{Attr: "region", Op: "eq", Value: "eu", IsAll: true},
+		},
+	}
+
+	tests := []struct {
+		name string
+		rule *config.CompiledRule
+		ctx  Context
+		want bool
+	}{
+		{"matches conditions", segmentOnly, Context{Key: "user:1", Attrs: map[string]any{"email": "a@ourco.com"}}, true},
+		{"fails conditions", segmentOnly, Context{Key: "user:1", Attrs: map[string]any{"email": "a@example.com"}}, false},
+		{"included key", segmentOnly, Context{Key: "user:contractor"}, true},
+		{"excluded key", segmentOnly, Context{Key: "user:intern", Attrs: map[string]any{"email": "i@ourco.com"}}, false},
+		{"segment and rule conditions", segmentAndCondition, Context{Key: "user:1", Attrs: map[string]any{"email": "a@ourco.com", "region": "eu"}}, true},
+		{"segment but not rule conditions", segmentAndCondition, Context{Key: "user:1", Attrs: map[string]any{"email": "a@ourco.com", "region": "us"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EvalRule(tt.rule, tt.ctx); got != tt.want {
+				t.Errorf("EvalRule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalRule_Expr(t *testing.T) {
+	cfg, err := config.LoadFromBytes([]byte(`
+version: 1
+segments:
+  staff:
+    when:
+      all:
+        - attr: email
+          op: ends_with
+          value: "@ourco.com"
+flags:
+  enterprise:
+    type: bool
+    variants: {"true": 0, "false": 100}
+    default: false
+    rules:
+      - expr: seats * price > 10000 && plan in ["pro", "ent"]
+        then:
+          variants: {"true": 100, "false": 0}
+      - expr: has(beta) && beta
+        segment: staff
+        then:
+          variants: {"true": 100, "false": 0}
+`))
+	if err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+	compiled, err := config.Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	rules := compiled.Flags["enterprise"].Rules
+
+	tests := []struct {
+		name  string
+		rule  int
+		attrs map[string]any
+		want  bool
+	}{
+		{"matches", 0, map[string]any{"seats": 25, "price": 450, "plan": "pro"}, true},
+		{"below threshold", 0, map[string]any{"seats": 2, "price": 450, "plan": "pro"}, false},
+		{"other plan", 0, map[string]any{"seats": 25, "price": 450, "plan": "free"}, false},
+		{"missing attribute", 0, map[string]any{"price": 450, "plan": "pro"}, false},
+		{"wrong type", 0, map[string]any{"seats": "25", "price": 450, "plan": "pro"}, false},
+		{"segment and expr", 1, map[string]any{"email": "a@ourco.com", "beta": true}, true},
+		{"segment but not expr", 1, map[string]any{"email": "a@ourco.com"}, false},
+		{"expr but not segment", 1, map[string]any{"email": "a@example.com", "beta": true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EvalRule(rules[tt.rule], Context{Key: "user:1", Attrs: tt.attrs}); got != tt.want {
+				t.Errorf("EvalRule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalRule_NestedConditions(t *testing.T) {
+	cfg, err := config.LoadFromBytes([]byte(`
+version: 1
+flags:
+  nested:
+    enabled: true
+    type: "bool"
+    rules:
+      - when:
+          all:
+            - attr: "plan"
+              op: "eq"
+              value: "pro"
+            - any:
+                - attr: "region"
+                  op: "eq"
+                  value: "us"
+                - attr: "beta"
+                  op: "eq"
+                  value: true
+          not:
+            attr: "country"
+            op: "in"
+            value: ["cn", "ru"]
+        then:
+          variants:
+            true: 100
+            false: 0
+    default: false
+`))
+	if err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+	compiled, err := config.Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	rule := compiled.Flags["nested"].Rules[0]
+	if rule.Conditions != nil {
+		t.Errorf("nested rule should not have flat Conditions, got %d", len(rule.Conditions))
+	}
+
+	tests := []struct {
+		name  string
+		attrs map[string]any
+		want  bool
+	}{
+		{"pro in us", map[string]any{"plan": "pro", "region": "us"}, true},
+		{"pro beta", map[string]any{"plan": "pro", "region": "eu", "beta": true}, true},
+		{"pro neither", map[string]any{"plan": "pro", "region": "eu"}, false},
+		{"free in us", map[string]any{"plan": "free", "region": "us"}, false},
+		{"excluded country", map[string]any{"plan": "pro", "region": "us", "country": "cn"}, false},
+		{"allowed country", map[string]any{"plan": "pro", "region": "us", "country": "de"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EvalRule(rule, Context{Key: "user:1", Attrs: tt.attrs}); got != tt.want {
+				t.Errorf("EvalRule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalCondition_Paths(t *testing.T) {
+	type org struct {
+		Plan  string `json:"plan_name"`
+		Seats int
+	}
+
+	attrs := map[string]any{
+		"org":      map[string]any{"plan": "pro", "seats": 40, "owner": nil},
+		"tags":     []any{"beta", "staff"},
+		"matrix":   [][]int{{1, 2}, {3, 4}},
+		"limits":   map[string]int{"api": 100},
+		"account":  &org{Plan: "enterprise", Seats: 3},
+		"org.plan": "literal",
+	}
+
+	tests := []struct {
+		name string
+		attr string
+		want any
+		ok   bool
+	}{
+		{"literal dotted key wins", "org.plan", "literal", true},
+		{"nested map", "org.seats", 40, true},
+		{"slice index", "tags[1]", "staff", true},
+		{"nested slices", "matrix[1][0]", 3, true},
+		{"typed map", "limits.api", 100, true},
+		{"struct json tag", "account.plan_name", "enterprise", true},
+		{"struct field name", "account.seats", 3, true},
+		{"missing intermediate", "team.plan", nil, false},
+		{"missing leaf", "org.region", nil, false},
+		{"nil intermediate", "org.owner.name", nil, false},
+		{"index out of range", "tags[2]", nil, false},
+		{"index into map", "org[0]", nil, false},
+		{"key into scalar", "org.seats.count", nil, false},
+		{"key into slice", "tags.first", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond := &config.CompiledCondition{Attr: tt.attr}
+			if config.IsPath(tt.attr) {
+				path, err := config.ParsePath(tt.attr)
+				if err != nil {
+					t.Fatalf("ParsePath() error = %v", err)
+				}
+				cond.Path = path
+			}
+
+			got, ok := lookupAttr(attrs, cond)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("lookupAttr() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestEvalCondition_RelativeTime(t *testing.T) {
+	operand, err := config.ParseTimeOperand("now+7d")
+	if err != nil {
+		t.Fatalf("ParseTimeOperand() error = %v", err)
+	}
+	cond := &config.CompiledCondition{Attr: "trial_ends_at", Op: "before", Value: operand}
+
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	ctx := Context{
+		Key:   "user:1",
+		Attrs: map[string]any{"trial_ends_at": "2026-03-05T00:00:00Z"},
+		Clock: func() time.Time { return now },
+	}
+	if !evalCondition(cond, ctx) {
+		t.Error("trial ending in 4 days should be within 7 days")
+	}
+
+	now = now.AddDate(0, 0, -10)
+	if evalCondition(cond, ctx) {
+		t.Error("trial ending in 14 days should not be within 7 days")
+	}
+}
+
+func TestEvalCondition_MissingAttribute(t *testing.T) {
+	tests := []struct {
+		op    string
+		value any
+		want  bool
+	}{
+		{"eq", "pro", false},
+		{"neq", "pro", false},
+		{"in", []any{"pro"}, false},
+		{"not_in", []any{"pro"}, false},
+		{"contains", "pro", false},
+		{"not_contains", "pro", false},
+		{"starts_with", "pro", false},
+		{"eq_ci", "pro", false},
+		{"exists", nil, false},
+		{"not_exists", nil, true},
+		{"gt", 1, false},
+		{"any_of", []any{"pro"}, false},
+		{"none_of", []any{"pro"}, true},
+	}
+
+	ctx := Context{Key: "user:1", Attrs: map[string]any{"org": map[string]any{}}}
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			for _, attr := range []string{"plan", "org.plan"} {
+				cond := &config.CompiledCondition{Attr: attr, Op: tt.op, Value: tt.value}
+				if attr == "org.plan" {
+					cond.Path = []config.PathSegment{{Key: "org"}, {Key: "plan"}}
+				}
+				if got := evalCondition(cond, ctx); got != tt.want {
+					t.Errorf("evalCondition(%s) with missing attribute = %v, want %v", attr, got, tt.want)
+				}
+			}
+		})
+	}
+	// Negating a positive condition is how to include contexts without the attribute
+	not := &config.CompiledNode{
+		Not: &config.CompiledNode{Condition: &config.CompiledCondition{Attr: "plan", Op: "eq", Value: "free"}},
+	}
+	if !evalNode(not, ctx) {
+		t.Error("not eq with missing attribute should match")
+	}
+}
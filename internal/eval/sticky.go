@@ -0,0 +1,29 @@
+package eval
+
+// StickyStore persists the variant each context was assigned by a split, so
+// that contexts keep their variant when the split's percentages change.
+//
+// Assignments are recorded together with the flag's hashed salt; an
+// assignment made under a different salt is treated as absent, so changing a
+// flag's salt starts every context afresh. Each split keeps its own
+// assignments: those made by the split of a rule are stored under the
+// context's key followed by "\x1frule:" and the rule's id, so that rules can
+// be reordered without reassigning anyone. Store errors are treated as cache
+// misses and never fail an evaluation.
+type StickyStore interface {
+	// Get returns the variant assigned to key for flagKey under salt.
+	Get(flagKey, key string, salt uint64) (variant string, ok bool, err error)
+	// Set records the variant assigned to key for flagKey under salt.
+	Set(flagKey, key string, salt uint64, variant string) error
+	// Reset removes every assignment for flagKey.
+	Reset(flagKey string) error
+}
+
+// stickyVariant returns the variant previously assigned to key, if any.
+func stickyVariant(store StickyStore, flagKey, key string, salt uint64) (string, bool) {
+	variant, ok, err := store.Get(flagKey, key, salt)
+	if err != nil || !ok {
+		return "", false
+	}
+	return variant, true
+}
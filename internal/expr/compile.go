@@ -0,0 +1,503 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// evalFunc evaluates a compiled node against the context attributes.
+type evalFunc func(attrs map[string]any) (Value, error)
+
+// typ is the static type of a node: a Kind, or typeAny for attributes and
+// other values only known during evaluation.
+type typ uint8
+
+const (
+	typeNull   = typ(KindNull)
+	typeBool   = typ(KindBool)
+	typeNumber = typ(KindNumber)
+	typeString = typ(KindString)
+	typeList   = typ(KindList)
+	typeAny    = typ(KindList + 1)
+)
+
+func (t typ) String() string {
+	if t == typeAny {
+		return "any"
+	}
+	return Kind(t).String()
+}
+
+// is reports whether a value of type t may have kind k.
+func (t typ) is(k Kind) bool {
+	return t == typeAny || t == typ(k)
+}
+
+type compiler struct {
+	src string
+}
+
+func (c *compiler) errorf(n node, format string, args ...any) *Error {
+	return &Error{Src: c.src, Pos: n.pos(), Msg: fmt.Sprintf(format, args...)}
+}
+
+func (c *compiler) compile(n node) (evalFunc, typ, error) {
+	switch n := n.(type) {
+	case *literal:
+		v := n.val
+		return func(map[string]any) (Value, error) { return v, nil }, typ(v.kind), nil
+	case *attrRef:
+		return compileAttr(n), typeAny, nil
+	case *listLit:
+		return c.compileList(n)
+	case *unary:
+		return c.compileUnary(n)
+	case *binary:
+		return c.compileBinary(n)
+	case *call:
+		return c.compileCall(n)
+	}
+	panic(fmt.Sprintf("expr: unknown node %T", n))
+}
+
+func compileAttr(n *attrRef) evalFunc {
+	name, path := n.name, n.path
+	return func(attrs map[string]any) (Value, error) {
+		a, ok := lookup(attrs, name, path)
+		if !ok {
+			return Value{}, nil
+		}
+		return valueOf(a), nil
+	}
+}
+
+// lookup resolves an attribute path. A top-level attribute whose name is the
+// whole path wins over walking nested maps and lists.
+func lookup(attrs map[string]any, name string, path []segment) (any, bool) {
+	if v, ok := attrs[name]; ok || len(path) == 1 {
+		return v, ok
+	}
+	v, ok := attrs[path[0].key]
+	for _, seg := range path[1:] {
+		if !ok {
+			return nil, false
+		}
+		if seg.isIndex {
+			v, ok = index(v, seg.index)
+		} else {
+			m, isMap := v.(map[string]any)
+			if !isMap {
+				return nil, false
+			}
+			v, ok = m[seg.key]
+		}
+	}
+	return v, ok
+}
+
+func index(v any, i int) (any, bool) {
+	switch l := v.(type) {
+	case []any:
+		if i < len(l) {
+			return l[i], true
+		}
+	case []string:
+		if i < len(l) {
+			return l[i], true
+		}
+	case []int:
+		if i < len(l) {
+			return l[i], true
+		}
+	case []int64:
+		if i < len(l) {
+			return l[i], true
+		}
+	case []float64:
+		if i < len(l) {
+			return l[i], true
+		}
+	}
+	return nil, false
+}
+
+func (c *compiler) compileList(n *listLit) (evalFunc, typ, error) {
+	elems := make([]evalFunc, len(n.elems))
+	constant := true
+	for i, elem := range n.elems {
+		fn, _, err := c.compile(elem)
+		if err != nil {
+			return nil, 0, err
+		}
+		elems[i] = fn
+		constant = constant && isConstant(elem)
+	}
+
+	// Lists that refer to no attributes are built once, unless an element
+	// fails, in which case the error is reported when it is evaluated
+	if constant {
+		if values, err := evalElems(elems, nil); err == nil {
+			v := Value{kind: KindList, list: values}
+			return func(map[string]any) (Value, error) { return v, nil }, typeList, nil
+		}
+	}
+	return func(attrs map[string]any) (Value, error) {
+		values, err := evalElems(elems, attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{kind: KindList, list: values}, nil
+	}, typeList, nil
+}
+
+func evalElems(elems []evalFunc, attrs map[string]any) ([]Value, error) {
+	values := make([]Value, len(elems))
+	for i, fn := range elems {
+		v, err := fn(attrs)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// isConstant reports whether n refers to no attributes, so that its value is
+// the same for every evaluation.
+func isConstant(n node) bool {
+	switch n := n.(type) {
+	case *literal:
+		return true
+	case *unary:
+		return isConstant(n.x)
+	case *binary:
+		return isConstant(n.x) && isConstant(n.y)
+	case *listLit:
+		for _, elem := range n.elems {
+			if !isConstant(elem) {
+				return false
+			}
+		}
+		return true
+	case *call:
+		for _, arg := range n.args {
+			if !isConstant(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (c *compiler) compileUnary(n *unary) (evalFunc, typ, error) {
+	x, t, err := c.compile(n.x)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if n.op == tokBang {
+		if !t.is(KindBool) {
+			return nil, 0, c.errorf(n, "operator ! requires a bool, got %s", t)
+		}
+		errType := c.errorf(n, "operator ! requires a bool")
+		return func(attrs map[string]any) (Value, error) {
+			v, err := x(attrs)
+			if err != nil {
+				return Value{}, err
+			}
+			if v.kind != KindBool {
+				return Value{}, errType
+			}
+			return Bool(!v.b), nil
+		}, typeBool, nil
+	}
+
+	if !t.is(KindNumber) {
+		return nil, 0, c.errorf(n, "operator - requires a number, got %s", t)
+	}
+	errType := c.errorf(n, "operator - requires a number")
+	return func(attrs map[string]any) (Value, error) {
+		v, err := x(attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		if v.kind != KindNumber {
+			return Value{}, errType
+		}
+		if v.isInt && v.i != math.MinInt64 {
+			return Int(-v.i), nil
+		}
+		return Float(-v.float()), nil
+	}, typeNumber, nil
+}
+
+func (c *compiler) compileBinary(n *binary) (evalFunc, typ, error) {
+	x, tx, err := c.compile(n.x)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n.op == tokIn {
+		return c.compileIn(n, x, tx)
+	}
+	y, ty, err := c.compile(n.y)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch n.op {
+	case tokAnd, tokOr:
+		if !tx.is(KindBool) || !ty.is(KindBool) {
+			return nil, 0, c.errorf(n, "operator %s requires bools, got %s and %s", n.op, tx, ty)
+		}
+		return logical(n.op == tokOr, x, y, c.errorf(n, "operator %s requires bools", n.op)), typeBool, nil
+
+	case tokEq, tokNeq:
+		if tx != ty && tx != typeAny && ty != typeAny && tx != typeNull && ty != typeNull {
+			return nil, 0, c.errorf(n, "mismatched types %s and %s", tx, ty)
+		}
+		want := n.op == tokEq
+		return func(attrs map[string]any) (Value, error) {
+			a, err := x(attrs)
+			if err != nil {
+				return Value{}, err
+			}
+			b, err := y(attrs)
+			if err != nil {
+				return Value{}, err
+			}
+			return Bool(equal(a, b) == want), nil
+		}, typeBool, nil
+
+	case tokLt, tokLte, tokGt, tokGte:
+		ordered := (tx.is(KindNumber) && ty.is(KindNumber)) || (tx.is(KindString) && ty.is(KindString))
+		if !ordered {
+			return nil, 0, c.errorf(n, "operator %s requires two numbers or two strings, got %s and %s", n.op, tx, ty)
+		}
+		return comparison(n.op, x, y, c.errorf(n, "operator %s requires two numbers or two strings", n.op)), typeBool, nil
+	}
+
+	if !tx.is(KindNumber) || !ty.is(KindNumber) {
+		return nil, 0, c.errorf(n, "operator %s requires numbers, got %s and %s", n.op, tx, ty)
+	}
+	return arithmetic(n.op, x, y, c.errorf(n, "operator %s requires numbers", n.op), c.errorf(n, "division by zero")), typeNumber, nil
+}
+
+func logical(or bool, x, y evalFunc, errType *Error) evalFunc {
+	return func(attrs map[string]any) (Value, error) {
+		a, err := x(attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		if a.kind != KindBool {
+			return Value{}, errType
+		}
+		if a.b == or {
+			return a, nil
+		}
+		b, err := y(attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		if b.kind != KindBool {
+			return Value{}, errType
+		}
+		return b, nil
+	}
+}
+
+func comparison(op tokenKind, x, y evalFunc, errType *Error) evalFunc {
+	return func(attrs map[string]any) (Value, error) {
+		a, err := x(attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		b, err := y(attrs)
+		if err != nil {
+			return Value{}, err
+		}
+
+		var c int
+		switch {
+		case a.kind == KindNumber && b.kind == KindNumber:
+			var ok bool
+			if c, ok = compareNumbers(a, b); !ok {
+				return Bool(false), nil
+			}
+		case a.kind == KindString && b.kind == KindString:
+			c = strings.Compare(a.s, b.s)
+		default:
+			return Value{}, errType
+		}
+
+		switch op {
+		case tokLt:
+			return Bool(c < 0), nil
+		case tokLte:
+			return Bool(c <= 0), nil
+		case tokGt:
+			return Bool(c > 0), nil
+		}
+		return Bool(c >= 0), nil
+	}
+}
+
+func arithmetic(op tokenKind, x, y evalFunc, errType, errZero *Error) evalFunc {
+	return func(attrs map[string]any) (Value, error) {
+		a, err := x(attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		b, err := y(attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		if a.kind != KindNumber || b.kind != KindNumber {
+			return Value{}, errType
+		}
+
+		if a.isInt && b.isInt {
+			if v, ok := intArithmetic(op, a.i, b.i); ok {
+				return v, nil
+			}
+		}
+		p, q := a.float(), b.float()
+		switch op {
+		case tokPlus:
+			return Float(p + q), nil
+		case tokMinus:
+			return Float(p - q), nil
+		case tokStar:
+			return Float(p * q), nil
+		}
+		if q == 0 {
+			return Value{}, errZero
+		}
+		if op == tokSlash {
+			return Float(p / q), nil
+		}
+		return Float(math.Mod(p, q)), nil
+	}
+}
+
+// intArithmetic computes an integer result exactly. ok is false if the
+// result overflows or is not an integer, in which case the caller falls back
+// to floats.
+func intArithmetic(op tokenKind, a, b int64) (Value, bool) {
+	switch op {
+	case tokPlus:
+		s := a + b
+		return Int(s), (s > a) == (b > 0)
+	case tokMinus:
+		d := a - b
+		return Int(d), (d < a) == (b > 0)
+	case tokStar:
+		if a == 0 || b == 0 {
+			return Int(0), true
+		}
+		p := a * b
+		return Int(p), p/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+	case tokSlash:
+		if b == 0 || a%b != 0 || (a == math.MinInt64 && b == -1) {
+			return Value{}, false
+		}
+		return Int(a / b), true
+	}
+	if b == 0 || b == -1 {
+		// Division by zero is reported by the float path; x % -1 is 0
+		return Int(0), b == -1
+	}
+	return Int(a % b), true
+}
+
+// compileIn compiles x in y and x not in y. The right side is a list, or a
+// string to search for a substring. A list literal is searched element by
+// element without being built.
+func (c *compiler) compileIn(n *binary, x evalFunc, tx typ) (evalFunc, typ, error) {
+	want := !n.not
+	name := "in"
+	if n.not {
+		name = "not in"
+	}
+
+	if list, ok := n.y.(*listLit); ok {
+		elems := make([]evalFunc, len(list.elems))
+		for i, elem := range list.elems {
+			fn, t, err := c.compile(elem)
+			if err != nil {
+				return nil, 0, err
+			}
+			if tx != t && tx != typeAny && t != typeAny && tx != typeNull && t != typeNull {
+				return nil, 0, c.errorf(elem, "mismatched types %s and %s in %s", tx, t, name)
+			}
+			elems[i] = fn
+		}
+		if isConstant(list) {
+			if values, err := evalElems(elems, nil); err == nil {
+				return func(attrs map[string]any) (Value, error) {
+					a, err := x(attrs)
+					if err != nil {
+						return Value{}, err
+					}
+					for _, b := range values {
+						if equal(a, b) {
+							return Bool(want), nil
+						}
+					}
+					return Bool(!want), nil
+				}, typeBool, nil
+			}
+		}
+		return func(attrs map[string]any) (Value, error) {
+			a, err := x(attrs)
+			if err != nil {
+				return Value{}, err
+			}
+			for _, elem := range elems {
+				b, err := elem(attrs)
+				if err != nil {
+					return Value{}, err
+				}
+				if equal(a, b) {
+					return Bool(want), nil
+				}
+			}
+			return Bool(!want), nil
+		}, typeBool, nil
+	}
+
+	y, ty, err := c.compile(n.y)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ty.is(KindList) && !ty.is(KindString) {
+		return nil, 0, c.errorf(n, "operator %s requires a list or a string on the right, got %s", name, ty)
+	}
+	if ty == typeString && !tx.is(KindString) {
+		return nil, 0, c.errorf(n, "operator %s on a string requires a string on the left, got %s", name, tx)
+	}
+	errType := c.errorf(n, "operator %s requires a list, or strings on both sides", name)
+	return func(attrs map[string]any) (Value, error) {
+		a, err := x(attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		b, err := y(attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		switch {
+		case b.kind == KindList:
+			for i := range listLen(b) {
+				if equal(a, listAt(b, i)) {
+					return Bool(want), nil
+				}
+			}
+			return Bool(!want), nil
+		case b.kind == KindString && a.kind == KindString:
+			return Bool(strings.Contains(b.s, a.s) == want), nil
+		}
+		return Value{}, errType
+	}, typeBool, nil
+}
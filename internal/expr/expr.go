@@ -0,0 +1,83 @@
+// Package expr implements the expression language of targeting rules: a
+// small, side-effect-free language over context attributes, such as
+//
+//	seats * price > 10000 && plan in ["pro", "ent"]
+//
+// Expressions are parsed and type-checked once by Compile, and list literals
+// that refer to no attributes are built then too. Evaluation walks
+// precompiled closures and converts attribute values with type switches, so
+// it needs no reflection and allocates only for lower and upper when they
+// change their argument, and for list literals built from attributes.
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Program is a compiled expression.
+type Program struct {
+	src     string
+	eval    evalFunc
+	notBool *Error
+}
+
+// Error is a parse, type or evaluation error at a position in the source.
+type Error struct {
+	Src string
+	Pos int // byte offset
+	Msg string
+}
+
+// Error formats the error as line:column: message.
+func (e *Error) Error() string {
+	line, col := e.Position()
+	return fmt.Sprintf("%d:%d: %s", line, col, e.Msg)
+}
+
+// Position returns the 1-based line and column of the error.
+func (e *Error) Position() (line, col int) {
+	before := e.Src[:e.Pos]
+	line = strings.Count(before, "\n") + 1
+	col = len(before) - strings.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// Compile parses and type-checks src.
+func Compile(src string) (*Program, error) {
+	root, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiler{src: src}
+	fn, t, err := c.compile(root)
+	if err != nil {
+		return nil, err
+	}
+	if t != typeBool && t != typeAny {
+		return nil, c.errorf(root, "expression must be a bool, got %s", t)
+	}
+	return &Program{
+		src:     src,
+		eval:    fn,
+		notBool: c.errorf(root, "expression is not a bool"),
+	}, nil
+}
+
+// Eval evaluates the program against attrs. Referring to a missing attribute
+// yields null; using a value of the wrong type is an error.
+func (p *Program) Eval(attrs map[string]any) (bool, error) {
+	v, err := p.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	if v.kind != KindBool {
+		return false, p.notBool
+	}
+	return v.b, nil
+}
+
+// String returns the source of the program.
+func (p *Program) String() string {
+	return p.src
+}
@@ -0,0 +1,264 @@
+package expr
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	attrs := map[string]any{
+		"plan":     "pro",
+		"seats":    25,
+		"price":    json.Number("450"),
+		"ratio":    0.5,
+		"beta":     true,
+		"country":  "GB",
+		"tags":     []string{"early", "internal"},
+		"scores":   []any{3, 7.5, "x"},
+		"org":      map[string]any{"plan": "ent", "teams": []any{map[string]any{"size": 12}}},
+		"org.tier": "gold", // a dotted top-level key wins over the path
+		"big":      int64(math.MaxInt64),
+		"huge":     uint64(math.MaxUint64),
+		"email":    "Ada@Example.com",
+		"empty":    nil,
+	}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`seats * price > 10000 && plan in ["pro", "ent"]`, true},
+		{`seats * price > 20000`, false},
+		{`plan == "pro"`, true},
+		{`plan != "pro"`, false},
+		{`plan == 'pro'`, true},
+		{`seats == 25.0`, true},
+		{`seats / 2 == 12.5`, true},
+		{`seats % 7 == 4`, true},
+		{`-seats < 0`, true},
+		{`1 + 2 * 3 == 7`, true},
+		{`(1 + 2) * 3 == 9`, true},
+		{`ratio * 4 == 2`, true},
+		{`beta`, true},
+		{`!beta`, false},
+		{`beta && !false || false`, true},
+		{`"internal" in tags`, true},
+		{`"staff" not in tags`, true},
+		{`7.5 in scores`, true},
+		{`country in ["US", "CA"]`, false},
+		{`country not in ["US", "CA"]`, true},
+		{`"Ex" in email`, true},
+		{`org.plan == "ent"`, true},
+		{`org.teams[0].size >= 12`, true},
+		{`org.tier == "gold"`, true},
+		{`tags[1] == "internal"`, true},
+		{`tags[5] == null`, true},
+		{`big == 9223372036854775807`, true},
+		{`big > 9223372036854775806`, true},
+		{`big + 1 > big`, true}, // overflows into a float
+		{`huge > big`, true},
+		{`"a" < "b"`, true},
+		{`len(tags) == 2`, true},
+		{`len("héllo") == 5`, true},
+		{`lower(email) == "ada@example.com"`, true},
+		{`upper(country) == "GB"`, true},
+		{`ends_with(lower(email), "@example.com")`, true},
+		{`starts_with(plan, "p") && contains(plan, "r")`, true},
+		{`has(plan)`, true},
+		{`has(missing)`, false},
+		{`has(empty)`, false},
+		{`has(org.plan)`, true},
+		{`has(org.nope)`, false},
+		{`missing == null`, true},
+		{`missing != "pro"`, true},
+		{`missing in ["pro"]`, false},
+		{`false && missing > 3`, false}, // short-circuit skips the error
+		{`true || missing > 3`, true},
+		{`[1, 2] == [1, 2.0]`, true},
+		{`[seats, 1] == [25, 1]`, true},
+		{`seats in [1, seats]`, true},
+		{"plan == \"pro\" &&\n  seats > 10", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			p, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := p.Eval(attrs)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEval_Errors(t *testing.T) {
+	attrs := map[string]any{"plan": "pro", "seats": 0, "beta": "yes"}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`missing > 3`, "1:9: operator > requires two numbers or two strings"},
+		{`plan * 2 > 1`, "1:6: operator * requires numbers"},
+		{`10 / seats > 1`, "1:4: division by zero"},
+		{`10 % seats > 1`, "1:4: division by zero"},
+		{`beta && true`, "1:6: operator && requires bools"},
+		{`!beta`, "1:1: operator ! requires a bool"},
+		{`beta`, "1:1: expression is not a bool"},
+		{`lower(seats) == "0"`, "1:7: argument 1 of lower must be a string"},
+		{`"x" in seats`, "1:5: operator in requires a list, or strings on both sides"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			p, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := p.Eval(attrs)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Eval() = %v, %v, want error %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{``, "1:1: unexpected end of expression"},
+		{`plan ==`, "1:8: unexpected end of expression"},
+		{`plan = "pro"`, `1:6: unexpected character '='`},
+		{`plan == "pro`, "1:9: unterminated string"},
+		{`plan == "\q"`, `1:10: unknown escape \q`},
+		{`(seats > 1`, `1:11: expected ")", found end of expression`},
+		{`seats > 1)`, `1:10: unexpected ")"`},
+		{`plan plan`, `1:6: unexpected identifier "plan"`},
+		{`1 < seats < 3`, "1:11: comparisons cannot be chained; use && to combine them"},
+		{`tags[-1] == "x"`, "1:6: index must be a non-negative integer"},
+		{`tags[i] == "x"`, "1:6: index must be a non-negative integer"},
+		{`org. == "x"`, `1:6: expected identifier`},
+		{`plan not "x"`, `1:10: expected "in"`},
+		{`1e999 > seats`, "1:1: invalid number 1e999"},
+		{`"a" * 2 > 1`, "1:5: operator * requires numbers, got string and number"},
+		{`1 > "10"`, "1:3: operator > requires two numbers or two strings, got number and string"},
+		{`plan == 1 && "x" == 2`, "1:18: mismatched types string and number"},
+		{`plan in ["a", 1 + 1] && 1 in ["a"]`, `1:31: mismatched types number and string in in`},
+		{`!1`, "1:1: operator ! requires a bool, got number"},
+		{`-"a" < 0`, "1:1: operator - requires a number, got string"},
+		{`1 && beta`, "1:3: operator && requires bools, got number and any"},
+		{`seats in 3`, "1:7: operator in requires a list or a string on the right, got number"},
+		{`1 in "abc"`, "1:3: operator in on a string requires a string on the left, got number"},
+		{`seats + 1`, "1:7: expression must be a bool, got number"},
+		{`"pro"`, "1:1: expression must be a bool, got string"},
+		{`shout(plan)`, `1:1: unknown function "shout"`},
+		{`lower(plan, plan) == ""`, "1:1: lower takes 1 arguments, got 2"},
+		{`lower(1) == ""`, "1:7: argument 1 of lower must be a string, got number"},
+		{`len(1) == 0`, "1:5: len requires a string or a list, got number"},
+		{`has("plan")`, "1:5: has requires an attribute"},
+		{"plan == \"pro\" &&\n  seats >", "2:10: unexpected end of expression"},
+		{"plan == \"pro\" &&\n  seats + true > 1", "2:9: operator + requires numbers, got any and bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Compile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_NestingLimit(t *testing.T) {
+	src := ""
+	for range maxDepth + 1 {
+		src += "("
+	}
+	if _, err := Compile(src + "true"); err == nil {
+		t.Error("Compile() of deeply nested expression succeeded")
+	}
+	if _, err := Compile("((((true))))"); err != nil {
+		t.Errorf("Compile() error = %v", err)
+	}
+}
+
+func TestEval_ZeroAllocs(t *testing.T) {
+	p, err := Compile(`seats * price > 10000 && plan in ["pro", "ent"] && "internal" in tags && org.teams[0].size >= 12 && starts_with(plan, "p")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := map[string]any{
+		"plan":  "pro",
+		"seats": 25,
+		"price": 450.0,
+		"tags":  []any{"early", "internal"},
+		"org":   map[string]any{"teams": []any{map[string]any{"size": 12}}},
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if ok, err := p.Eval(attrs); !ok || err != nil {
+			t.Fatalf("Eval() = %v, %v", ok, err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Eval() allocs = %v, want 0", allocs)
+	}
+}
+
+func TestEval_Allocs(t *testing.T) {
+	attrs := map[string]any{"plan": "pro", "seats": 40, "name": "Jane"}
+
+	tests := []struct {
+		expr      string
+		allocates bool
+	}{
+		{`seats in [-1, 2 * 20, len("abc")]`, false},
+		{`plan in [lower("PRO"), "ent"]`, false},
+		{`[1, [2, 3]] != [seats]`, true},
+		{`plan in [name, "pro"]`, false},
+		{`plan in [lower(name), "pro"]`, true},
+		{`lower(plan) == "pro"`, false},
+		{`lower(name) == "jane"`, true},
+		{`upper(name) == "JANE"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allocs := testing.AllocsPerRun(100, func() {
+				if ok, err := p.Eval(attrs); !ok || err != nil {
+					t.Fatalf("Eval() = %v, %v", ok, err)
+				}
+			})
+			if (allocs != 0) != tt.allocates {
+				t.Errorf("Eval() allocs = %v, want allocations %v", allocs, tt.allocates)
+			}
+		})
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	p, err := Compile(`seats * price > 10000 && plan in ["pro", "ent"]`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	attrs := map[string]any{"plan": "ent", "seats": 25, "price": 450}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		p.Eval(attrs)
+	}
+}
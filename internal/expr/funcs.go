@@ -0,0 +1,126 @@
+package expr
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// builtin is a function callable from expressions.
+type builtin struct {
+	params []Kind
+	result typ
+	fn     func(a, b Value) Value // b is unused by one-argument builtins
+}
+
+var builtins = map[string]builtin{
+	"lower": {
+		params: []Kind{KindString},
+		result: typeString,
+		fn:     func(a, _ Value) Value { return String(strings.ToLower(a.s)) },
+	},
+	"upper": {
+		params: []Kind{KindString},
+		result: typeString,
+		fn:     func(a, _ Value) Value { return String(strings.ToUpper(a.s)) },
+	},
+	"contains": {
+		params: []Kind{KindString, KindString},
+		result: typeBool,
+		fn:     func(a, b Value) Value { return Bool(strings.Contains(a.s, b.s)) },
+	},
+	"starts_with": {
+		params: []Kind{KindString, KindString},
+		result: typeBool,
+		fn:     func(a, b Value) Value { return Bool(strings.HasPrefix(a.s, b.s)) },
+	},
+	"ends_with": {
+		params: []Kind{KindString, KindString},
+		result: typeBool,
+		fn:     func(a, b Value) Value { return Bool(strings.HasSuffix(a.s, b.s)) },
+	},
+}
+
+// compileCall compiles a call to a builtin. len and has are special forms:
+// len accepts strings and lists, and has takes an attribute path rather
+// than its value.
+func (c *compiler) compileCall(n *call) (evalFunc, typ, error) {
+	switch n.fn {
+	case "has":
+		if len(n.args) != 1 {
+			return nil, 0, c.errorf(n, "has takes 1 argument, got %d", len(n.args))
+		}
+		ref, ok := n.args[0].(*attrRef)
+		if !ok {
+			return nil, 0, c.errorf(n.args[0], "has requires an attribute")
+		}
+		name, path := ref.name, ref.path
+		return func(attrs map[string]any) (Value, error) {
+			v, ok := lookup(attrs, name, path)
+			return Bool(ok && v != nil), nil
+		}, typeBool, nil
+
+	case "len":
+		if len(n.args) != 1 {
+			return nil, 0, c.errorf(n, "len takes 1 argument, got %d", len(n.args))
+		}
+		x, t, err := c.compile(n.args[0])
+		if err != nil {
+			return nil, 0, err
+		}
+		if !t.is(KindString) && !t.is(KindList) {
+			return nil, 0, c.errorf(n.args[0], "len requires a string or a list, got %s", t)
+		}
+		errType := c.errorf(n.args[0], "len requires a string or a list")
+		return func(attrs map[string]any) (Value, error) {
+			v, err := x(attrs)
+			if err != nil {
+				return Value{}, err
+			}
+			switch v.kind {
+			case KindString:
+				return Int(int64(utf8.RuneCountInString(v.s))), nil
+			case KindList:
+				return Int(int64(listLen(v))), nil
+			}
+			return Value{}, errType
+		}, typeNumber, nil
+	}
+
+	b, ok := builtins[n.fn]
+	if !ok {
+		return nil, 0, c.errorf(n, "unknown function %q", n.fn)
+	}
+	if len(n.args) != len(b.params) {
+		return nil, 0, c.errorf(n, "%s takes %d arguments, got %d", n.fn, len(b.params), len(n.args))
+	}
+	args := make([]evalFunc, len(n.args))
+	errTypes := make([]*Error, len(n.args))
+	for i, arg := range n.args {
+		fn, t, err := c.compile(arg)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !t.is(b.params[i]) {
+			return nil, 0, c.errorf(arg, "argument %d of %s must be a %s, got %s", i+1, n.fn, b.params[i], t)
+		}
+		args[i] = fn
+		errTypes[i] = c.errorf(arg, "argument %d of %s must be a %s", i+1, n.fn, b.params[i])
+	}
+
+	params, fn := b.params, b.fn
+	return func(attrs map[string]any) (Value, error) {
+		// Builtins take at most two arguments
+		var values [2]Value
+		for i, arg := range args {
+			v, err := arg(attrs)
+			if err != nil {
+				return Value{}, err
+			}
+			if v.kind != params[i] {
+				return Value{}, errTypes[i]
+			}
+			values[i] = v
+		}
+		return fn(values[0], values[1]), nil
+	}, b.result, nil
+}
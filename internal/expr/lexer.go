@@ -0,0 +1,261 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokTrue
+	tokFalse
+	tokNull
+	tokIn
+	tokNot
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+	tokDot
+	tokBang
+	tokPlus
+	tokMinus
+	tokStar
+	tokSlash
+	tokPercent
+	tokEq
+	tokNeq
+	tokLt
+	tokLte
+	tokGt
+	tokGte
+	tokAnd
+	tokOr
+)
+
+var tokenNames = [...]string{
+	tokEOF:      "end of expression",
+	tokIdent:    "identifier",
+	tokNumber:   "number",
+	tokString:   "string",
+	tokTrue:     "true",
+	tokFalse:    "false",
+	tokNull:     "null",
+	tokIn:       "in",
+	tokNot:      "not",
+	tokLParen:   "(",
+	tokRParen:   ")",
+	tokLBracket: "[",
+	tokRBracket: "]",
+	tokComma:    ",",
+	tokDot:      ".",
+	tokBang:     "!",
+	tokPlus:     "+",
+	tokMinus:    "-",
+	tokStar:     "*",
+	tokSlash:    "/",
+	tokPercent:  "%",
+	tokEq:       "==",
+	tokNeq:      "!=",
+	tokLt:       "<",
+	tokLte:      "<=",
+	tokGt:       ">",
+	tokGte:      ">=",
+	tokAnd:      "&&",
+	tokOr:       "||",
+}
+
+func (k tokenKind) String() string {
+	return tokenNames[k]
+}
+
+var keywords = map[string]tokenKind{
+	"true":  tokTrue,
+	"false": tokFalse,
+	"null":  tokNull,
+	"in":    tokIn,
+	"not":   tokNot,
+}
+
+type token struct {
+	kind tokenKind
+	pos  int    // byte offset in the source
+	text string // identifier name, number literal or unquoted string
+}
+
+// lex splits src into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for {
+		for i < len(src) && isSpace(src[i]) {
+			i++
+		}
+		if i == len(src) {
+			return append(tokens, token{kind: tokEOF, pos: i}), nil
+		}
+
+		start := i
+		c := src[i]
+		switch {
+		case isLetter(c):
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			word := src[start:i]
+			if kind, ok := keywords[word]; ok {
+				tokens = append(tokens, token{kind: kind, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokIdent, pos: start, text: word})
+			}
+			continue
+		case isDigit(c):
+			i = scanNumber(src, i)
+			tokens = append(tokens, token{kind: tokNumber, pos: start, text: src[start:i]})
+			continue
+		case c == '"' || c == '\'':
+			s, end, err := scanString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokString, pos: start, text: s})
+			continue
+		}
+
+		kind, width := operator(src[i:])
+		if width == 0 {
+			return nil, &Error{Src: src, Pos: start, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+		i += width
+		tokens = append(tokens, token{kind: kind, pos: start})
+	}
+}
+
+// operator returns the punctuation token at the start of s, longest first.
+func operator(s string) (tokenKind, int) {
+	if len(s) >= 2 {
+		switch s[:2] {
+		case "==":
+			return tokEq, 2
+		case "!=":
+			return tokNeq, 2
+		case "<=":
+			return tokLte, 2
+		case ">=":
+			return tokGte, 2
+		case "&&":
+			return tokAnd, 2
+		case "||":
+			return tokOr, 2
+		}
+	}
+	switch s[0] {
+	case '(':
+		return tokLParen, 1
+	case ')':
+		return tokRParen, 1
+	case '[':
+		return tokLBracket, 1
+	case ']':
+		return tokRBracket, 1
+	case ',':
+		return tokComma, 1
+	case '.':
+		return tokDot, 1
+	case '!':
+		return tokBang, 1
+	case '+':
+		return tokPlus, 1
+	case '-':
+		return tokMinus, 1
+	case '*':
+		return tokStar, 1
+	case '/':
+		return tokSlash, 1
+	case '%':
+		return tokPercent, 1
+	case '<':
+		return tokLt, 1
+	case '>':
+		return tokGt, 1
+	}
+	return tokEOF, 0
+}
+
+// scanNumber returns the end of the number starting at i: digits with an
+// optional fraction and exponent.
+func scanNumber(src string, i int) int {
+	for i < len(src) && isDigit(src[i]) {
+		i++
+	}
+	if i+1 < len(src) && src[i] == '.' && isDigit(src[i+1]) {
+		i++
+		for i < len(src) && isDigit(src[i]) {
+			i++
+		}
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		j := i + 1
+		if j < len(src) && (src[j] == '+' || src[j] == '-') {
+			j++
+		}
+		if j < len(src) && isDigit(src[j]) {
+			i = j
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+// scanString reads a string quoted with ' or " starting at i and returns its
+// value and the offset after the closing quote. Supported escapes are \\,
+// \", \', \n and \t.
+func scanString(src string, i int) (string, int, error) {
+	quote := src[i]
+	var b strings.Builder
+	for j := i + 1; j < len(src); j++ {
+		switch c := src[j]; c {
+		case quote:
+			return b.String(), j + 1, nil
+		case '\\':
+			if j+1 == len(src) {
+				break
+			}
+			j++
+			switch src[j] {
+			case '\\', '"', '\'':
+				b.WriteByte(src[j])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				return "", 0, &Error{Src: src, Pos: j - 1, Msg: fmt.Sprintf("unknown escape \\%c", src[j])}
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, &Error{Src: src, Pos: i, Msg: "unterminated string"}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
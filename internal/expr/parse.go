@@ -0,0 +1,325 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// maxDepth bounds nesting so hostile input cannot exhaust the stack.
+const maxDepth = 64
+
+type node interface {
+	pos() int
+}
+
+type (
+	literal struct {
+		at  int
+		val Value
+	}
+	// attrRef is an attribute path such as org.plan or tags[0].
+	attrRef struct {
+		at   int
+		name string // source form, for messages
+		path []segment
+	}
+	listLit struct {
+		at    int
+		elems []node
+	}
+	unary struct {
+		at int
+		op tokenKind
+		x  node
+	}
+	binary struct {
+		at   int // position of the operator
+		op   tokenKind
+		not  bool // "not in"
+		x, y node
+	}
+	call struct {
+		at   int
+		fn   string
+		args []node
+	}
+)
+
+func (n *literal) pos() int { return n.at }
+func (n *attrRef) pos() int { return n.at }
+func (n *listLit) pos() int { return n.at }
+func (n *unary) pos() int   { return n.at }
+func (n *binary) pos() int  { return n.at }
+func (n *call) pos() int    { return n.at }
+
+// segment is one step of an attribute path: a map key, or a list index when
+// isIndex is set.
+type segment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// Binary operator precedence; higher binds tighter.
+var precedence = map[tokenKind]int{
+	tokOr:      1,
+	tokAnd:     2,
+	tokEq:      3,
+	tokNeq:     3,
+	tokLt:      3,
+	tokLte:     3,
+	tokGt:      3,
+	tokGte:     3,
+	tokIn:      3,
+	tokNot:     3, // not in
+	tokPlus:    4,
+	tokMinus:   4,
+	tokStar:    5,
+	tokSlash:   5,
+	tokPercent: 5,
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	i      int
+	depth  int
+}
+
+// parse builds the syntax tree of src.
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens}
+	n, err := p.expr(1)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.unexpected(tok)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+	return &Error{Src: p.src, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) unexpected(tok token) error {
+	switch tok.kind {
+	case tokIdent:
+		return p.errorf(tok.pos, "unexpected identifier %q", tok.text)
+	case tokNumber:
+		return p.errorf(tok.pos, "unexpected number %s", tok.text)
+	case tokString:
+		return p.errorf(tok.pos, "unexpected string %q", tok.text)
+	case tokEOF:
+		return p.errorf(tok.pos, "unexpected end of expression")
+	}
+	return p.errorf(tok.pos, "unexpected %q", tok.kind)
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		what := fmt.Sprintf("%q", kind)
+		if kind == tokIdent {
+			what = "identifier"
+		}
+		if tok.kind == tokEOF {
+			return tok, p.errorf(tok.pos, "expected %s, found end of expression", what)
+		}
+		return tok, p.errorf(tok.pos, "expected %s", what)
+	}
+	return tok, nil
+}
+
+// expr parses binary operators of at least the given precedence.
+func (p *parser) expr(minPrec int) (node, error) {
+	if p.depth++; p.depth > maxDepth {
+		return nil, p.errorf(p.peek().pos, "expression nested too deeply")
+	}
+	defer func() { p.depth-- }()
+
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		prec, ok := precedence[tok.kind]
+		if !ok || prec < minPrec {
+			return x, nil
+		}
+		p.next()
+		op, not := tok.kind, false
+		if op == tokNot {
+			if _, err := p.expect(tokIn); err != nil {
+				return nil, err
+			}
+			op, not = tokIn, true
+		}
+		// Comparisons do not chain: a < b < c is an error
+		next := prec + 1
+		y, err := p.expr(next)
+		if err != nil {
+			return nil, err
+		}
+		x = &binary{at: tok.pos, op: op, not: not, x: x, y: y}
+		if prec == 3 {
+			if following := p.peek(); precedence[following.kind] == 3 {
+				return nil, p.errorf(following.pos, "comparisons cannot be chained; use && to combine them")
+			}
+		}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	tok := p.peek()
+	if tok.kind != tokBang && tok.kind != tokMinus {
+		return p.primary()
+	}
+	p.next()
+	if p.depth++; p.depth > maxDepth {
+		return nil, p.errorf(tok.pos, "expression nested too deeply")
+	}
+	defer func() { p.depth-- }()
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &unary{at: tok.pos, op: tok.kind, x: x}, nil
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		v, err := parseNumber(tok.text)
+		if err != nil {
+			return nil, p.errorf(tok.pos, "invalid number %s", tok.text)
+		}
+		return &literal{at: tok.pos, val: v}, nil
+	case tokString:
+		return &literal{at: tok.pos, val: String(tok.text)}, nil
+	case tokTrue, tokFalse:
+		return &literal{at: tok.pos, val: Bool(tok.kind == tokTrue)}, nil
+	case tokNull:
+		return &literal{at: tok.pos, val: Value{}}, nil
+	case tokLParen:
+		x, err := p.expr(1)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case tokLBracket:
+		list := &listLit{at: tok.pos}
+		for p.peek().kind != tokRBracket {
+			elem, err := p.expr(1)
+			if err != nil {
+				return nil, err
+			}
+			list.elems = append(list.elems, elem)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(tokRBracket); err != nil {
+			return nil, err
+		}
+		return list, nil
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.call(tok)
+		}
+		return p.attr(tok)
+	}
+	return nil, p.unexpected(tok)
+}
+
+func (p *parser) call(name token) (node, error) {
+	p.next() // (
+	c := &call{at: name.pos, fn: name.text}
+	for p.peek().kind != tokRParen {
+		arg, err := p.expr(1)
+		if err != nil {
+			return nil, err
+		}
+		c.args = append(c.args, arg)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// attr parses an attribute path. Indexes must be integer literals so that
+// the path is fixed at compile time.
+func (p *parser) attr(name token) (node, error) {
+	ref := &attrRef{at: name.pos, path: []segment{{key: name.text}}}
+	end := name.pos + len(name.text)
+	for {
+		switch p.peek().kind {
+		case tokDot:
+			p.next()
+			key, err := p.expect(tokIdent)
+			if err != nil {
+				return nil, err
+			}
+			ref.path = append(ref.path, segment{key: key.text})
+			end = key.pos + len(key.text)
+			continue
+		case tokLBracket:
+			p.next()
+			idx := p.next()
+			n, err := strconv.Atoi(idx.text)
+			if idx.kind != tokNumber || err != nil || n < 0 {
+				return nil, p.errorf(idx.pos, "index must be a non-negative integer")
+			}
+			closing, err := p.expect(tokRBracket)
+			if err != nil {
+				return nil, err
+			}
+			ref.path = append(ref.path, segment{index: n, isIndex: true})
+			end = closing.pos + 1
+			continue
+		}
+		ref.name = p.src[name.pos:end]
+		return ref, nil
+	}
+}
+
+// parseNumber parses a number literal as an integer if it has no fraction
+// or exponent and fits in an int64, and as a float otherwise.
+func parseNumber(text string) (Value, error) {
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return Int(i), nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return Value{}, err
+	}
+	return Float(f), nil
+}
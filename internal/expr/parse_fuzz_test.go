@@ -0,0 +1,51 @@
+package expr
+
+import (
+	"testing"
+)
+
+func FuzzCompile(f *testing.F) {
+	// Seed with valid and almost valid expressions
+	for _, src := range []string{
+		`seats * price > 10000 && plan in ["pro", "ent"]`,
+		`!(country not in ["US", "CA"]) || has(org.teams[0].size)`,
+		`len(lower(email)) % 3 == -1.5e2`,
+		`starts_with(plan, 'p\'') && tags[2] != null`,
+		"a ==\n 'x",
+		`((1 < 2)`,
+	} {
+		f.Add(src)
+	}
+
+	attrs := map[string]any{
+		"plan":  "pro",
+		"seats": 3,
+		"price": -2.5,
+		"tags":  []any{"a", 1, nil},
+		"org":   map[string]any{"teams": []any{map[string]any{"size": 0}}},
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		// Should not panic
+		p, err := Compile(src)
+		if err != nil {
+			// Errors must point into the source
+			e, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Compile() error %T, want *Error", err)
+			}
+			if e.Pos < 0 || e.Pos > len(src) {
+				t.Fatalf("Compile() error position %d outside source of length %d", e.Pos, len(src))
+			}
+			return
+		}
+
+		// Evaluation should not panic and should be deterministic
+		got, err := p.Eval(attrs)
+		again, errAgain := p.Eval(attrs)
+		if got != again || (err == nil) != (errAgain == nil) {
+			t.Errorf("Eval() = %v, %v then %v, %v", got, err, again, errAgain)
+		}
+		p.Eval(nil)
+	})
+}
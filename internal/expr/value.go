@@ -0,0 +1,233 @@
+package expr
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// Kind is the dynamic type of a Value.
+type Kind uint8
+
+const (
+	KindNull Kind = iota
+	KindBool
+	KindNumber
+	KindString
+	KindList
+)
+
+var kindNames = [...]string{
+	KindNull:   "null",
+	KindBool:   "bool",
+	KindNumber: "number",
+	KindString: "string",
+	KindList:   "list",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Value is the result of evaluating an expression or any of its parts.
+// Numbers are integers when they can be, so comparisons between them are
+// exact; lists keep a reference to the attribute they came from.
+type Value struct {
+	kind  Kind
+	isInt bool
+	b     bool
+	i     int64
+	f     float64
+	s     string
+	list  any // []Value, []any, []string, []int, []int64 or []float64
+}
+
+// Bool returns a bool value.
+func Bool(b bool) Value { return Value{kind: KindBool, b: b} }
+
+// Int returns an integer value.
+func Int(i int64) Value { return Value{kind: KindNumber, isInt: true, i: i} }
+
+// Float returns a number value. Integral floats are stored as integers.
+func Float(f float64) Value {
+	if f == math.Trunc(f) && f >= -(1<<63) && f < 1<<63 {
+		return Int(int64(f))
+	}
+	return Value{kind: KindNumber, f: f}
+}
+
+// String returns a string value.
+func String(s string) Value { return Value{kind: KindString, s: s} }
+
+// Kind returns the dynamic type of v.
+func (v Value) Kind() Kind { return v.kind }
+
+// float returns a number value as a float64.
+func (v Value) float() float64 {
+	if v.isInt {
+		return float64(v.i)
+	}
+	return v.f
+}
+
+// valueOf converts an attribute value. Types outside the expression
+// language are treated as null.
+func valueOf(a any) Value {
+	switch a := a.(type) {
+	case string:
+		return String(a)
+	case bool:
+		return Bool(a)
+	case int:
+		return Int(int64(a))
+	case int8:
+		return Int(int64(a))
+	case int16:
+		return Int(int64(a))
+	case int32:
+		return Int(int64(a))
+	case int64:
+		return Int(a)
+	case uint:
+		return uintValue(uint64(a))
+	case uint8:
+		return Int(int64(a))
+	case uint16:
+		return Int(int64(a))
+	case uint32:
+		return Int(int64(a))
+	case uint64:
+		return uintValue(a)
+	case float32:
+		return Float(float64(a))
+	case float64:
+		return Float(a)
+	case json.Number:
+		if i, err := a.Int64(); err == nil {
+			return Int(i)
+		}
+		if f, err := strconv.ParseFloat(string(a), 64); err == nil {
+			return Float(f)
+		}
+	case []any, []string, []int, []int64, []float64:
+		return Value{kind: KindList, list: a}
+	}
+	return Value{}
+}
+
+func uintValue(u uint64) Value {
+	if u > math.MaxInt64 {
+		return Value{kind: KindNumber, f: float64(u)}
+	}
+	return Int(int64(u))
+}
+
+// listLen returns the number of elements of a list value.
+func listLen(v Value) int {
+	switch l := v.list.(type) {
+	case []Value:
+		return len(l)
+	case []any:
+		return len(l)
+	case []string:
+		return len(l)
+	case []int:
+		return len(l)
+	case []int64:
+		return len(l)
+	case []float64:
+		return len(l)
+	}
+	return 0
+}
+
+// listAt returns element i of a list value, which must be in range.
+func listAt(v Value, i int) Value {
+	switch l := v.list.(type) {
+	case []Value:
+		return l[i]
+	case []any:
+		return valueOf(l[i])
+	case []string:
+		return String(l[i])
+	case []int:
+		return Int(int64(l[i]))
+	case []int64:
+		return Int(l[i])
+	case []float64:
+		return Float(l[i])
+	}
+	return Value{}
+}
+
+// equal reports whether a and b are the same value. Values of different
+// kinds are never equal; lists are compared element by element.
+func equal(a, b Value) bool {
+	if a.kind != b.kind {
+		return false
+	}
+	switch a.kind {
+	case KindNull:
+		return true
+	case KindBool:
+		return a.b == b.b
+	case KindString:
+		return a.s == b.s
+	case KindNumber:
+		c, ok := compareNumbers(a, b)
+		return ok && c == 0
+	}
+	n := listLen(a)
+	if n != listLen(b) {
+		return false
+	}
+	for i := range n {
+		if !equal(listAt(a, i), listAt(b, i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareNumbers orders two numbers exactly. ok is false if either is NaN.
+func compareNumbers(a, b Value) (int, bool) {
+	switch {
+	case a.isInt && b.isInt:
+		return cmp3(a.i < b.i, a.i > b.i), true
+	case math.IsNaN(a.f) || math.IsNaN(b.f):
+		return 0, false
+	case a.isInt:
+		return compareIntFloat(a.i, b.f), true
+	case b.isInt:
+		return -compareIntFloat(b.i, a.f), true
+	}
+	return cmp3(a.f < b.f, a.f > b.f), true
+}
+
+// compareIntFloat orders an integer and a float without rounding the
+// integer to a float.
+func compareIntFloat(i int64, f float64) int {
+	const twoTo63 = 1 << 63 // exact as float64
+	switch {
+	case f >= twoTo63:
+		return -1
+	case f < -twoTo63:
+		return 1
+	}
+	// -2^63 <= f < 2^63, so its integer part converts exactly
+	t := math.Trunc(f)
+	if ti := int64(t); i != ti {
+		return cmp3(i < ti, i > ti)
+	}
+	return cmp3(f > t, f < t)
+}
+
+func cmp3(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a version range such as ">=2.3.0 <3". Comparators separated
+// by spaces must all hold; alternatives are separated by "||".
+type Constraint struct {
+	alternatives [][]comparator
+}
+
+type comparator struct {
+	op      string // "=", ">", ">=", "<" or "<="
+	version Version
+}
+
+// ParseConstraint parses a constraint. A comparator without an operator
+// means "=".
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, alt := range strings.Split(s, "||") {
+		fields := strings.Fields(alt)
+		if len(fields) == 0 {
+			return c, fmt.Errorf("invalid constraint %q: empty range", s)
+		}
+		comparators := make([]comparator, 0, len(fields))
+		for _, field := range fields {
+			cmp, err := parseComparator(field)
+			if err != nil {
+				return c, fmt.Errorf("invalid constraint %q: %w", s, err)
+			}
+			comparators = append(comparators, cmp)
+		}
+		c.alternatives = append(c.alternatives, comparators)
+	}
+	return c, nil
+}
+
+func parseComparator(s string) (comparator, error) {
+	op := "="
+	for _, prefix := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(s, prefix) {
+			op = prefix
+			s = s[len(prefix):]
+			break
+		}
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return comparator{}, err
+	}
+	return comparator{op: op, version: v}, nil
+}
+
+// Check reports whether v satisfies the constraint.
+func (c Constraint) Check(v Version) bool {
+	for _, comparators := range c.alternatives {
+		if allHold(comparators, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func allHold(comparators []comparator, v Version) bool {
+	for _, cmp := range comparators {
+		result := Compare(v, cmp.version)
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = result == 0
+		case ">":
+			ok = result > 0
+		case ">=":
+			ok = result >= 0
+		case "<":
+			ok = result < 0
+		case "<=":
+			ok = result <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+// Package semver parses and compares semantic versions.
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version. Build metadata is discarded since
+// it does not affect precedence.
+type Version struct {
+	Major, Minor, Patch uint64
+	Pre                 []string // pre-release identifiers, e.g. ["rc", "1"]
+}
+
+// Parse parses a version such as "1.10.0", "v2.3.0-rc.1" or "1.2+build.5".
+// Missing minor and patch numbers are taken as zero, so "3" is "3.0.0".
+func Parse(s string) (Version, error) {
+	var v Version
+	rest := strings.TrimPrefix(s, "v")
+	rest, _, _ = strings.Cut(rest, "+")
+	rest, pre, hasPre := strings.Cut(rest, "-")
+
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q: too many components", s)
+	}
+	nums := [3]*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := parseNumber(part)
+		if err != nil {
+			return v, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		*nums[i] = n
+	}
+
+	if hasPre {
+		v.Pre = strings.Split(pre, ".")
+		for _, id := range v.Pre {
+			if id == "" {
+				return v, fmt.Errorf("invalid version %q: empty pre-release identifier", s)
+			}
+			for _, r := range id {
+				if !isAlnum(r) && r != '-' {
+					return v, fmt.Errorf("invalid version %q: invalid pre-release identifier %q", s, id)
+				}
+			}
+		}
+	}
+	return v, nil
+}
+
+// parseNumber parses a version component: digits without leading zeros.
+func parseNumber(s string) (uint64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty component")
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("component %q has a leading zero", s)
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("component %q is not a number", s)
+		}
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func isAlnum(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// String formats v without a leading "v".
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Pre) > 0 {
+		s += "-" + strings.Join(v.Pre, ".")
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as a is lower than, equal to or higher than b,
+// following semver precedence: a pre-release is lower than its release.
+func Compare(a, b Version) int {
+	if c := compareUint(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+
+	switch {
+	case len(a.Pre) == 0 && len(b.Pre) == 0:
+		return 0
+	case len(a.Pre) == 0:
+		return 1
+	case len(b.Pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.Pre) && i < len(b.Pre); i++ {
+		if c := compareIdentifier(a.Pre[i], b.Pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(a.Pre)), uint64(len(b.Pre)))
+}
+
+// compareIdentifier compares pre-release identifiers: numeric ones
+// numerically and lower than alphanumeric ones, which compare in ASCII order.
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "1.10.0", want: "1.10.0"},
+		{in: "v2.3.0-rc.1", want: "2.3.0-rc.1"},
+		{in: "1.2+build.5", want: "1.2.0"},
+		{in: "3", want: "3.0.0"},
+		{in: "", wantErr: true},
+		{in: "1..2", wantErr: true},
+		{in: "1.2.3.4", wantErr: true},
+		{in: "01.2.3", wantErr: true},
+		{in: "1.x", wantErr: true},
+		{in: "1.2.3-", wantErr: true},
+		{in: "1.2.3-rc..1", wantErr: true},
+		{in: "1.2.3-rc_1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// In ascending order of precedence
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.9.2",
+		"1.10.0",
+		"2.0.0",
+	}
+
+	for i, a := range ordered {
+		for j, b := range ordered {
+			va, _ := Parse(a)
+			vb, _ := Parse(b)
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := Compare(va, vb); got != want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=2.3.0 <3", "2.3.0", true},
+		{">=2.3.0 <3", "2.10.1", true},
+		{">=2.3.0 <3", "3.0.0", false},
+		{">=2.3.0 <3", "2.2.9", false},
+		{">=2.3.0 <3", "3.0.0-rc.1", true},
+		{"1.2.3", "1.2.3", true},
+		{"=1.2.3", "1.2.4", false},
+		{"<1 || >=2", "0.9.0", true},
+		{"<1 || >=2", "1.5.0", false},
+		{"<1 || >=2", "2.0.0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint() error = %v", err)
+			}
+			v, err := Parse(tt.version)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := c.Check(v); got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+	for _, s := range []string{"", ">=", ">=2.3.0 ||", "~1.2", ">=1.x"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package goff
+
+import (
+	"slices"
+
+	"github.com/0mjs/goff/internal/config"
+	"github.com/0mjs/goff/internal/eval"
+)
+
+// AllFlagsOptions filters the flags evaluated by AllFlags.
+type AllFlagsOptions struct {
+	// ClientSideOnly restricts the result to flags marked client_side: true,
+	// so that server-only flags are never sent to browsers.
+	ClientSideOnly bool
+	// Tags restricts the result to flags carrying at least one of the tags.
+	Tags []string
+}
+
+// FlagState is the evaluated state of a single flag returned by AllFlags.
+type FlagState struct {
+	Value   any    `json:"value"` // bool, string, int64, float64 or decoded json; nil if no value applies
+	Variant string `json:"variant,omitempty"`
+	Reason  Reason `json:"reason"`
+}
+
+// AllFlags evaluates every flag in the current snapshot for ctx in one pass.
+// AfterEval hooks are not called for bulk evaluations.
+func (c *client) AllFlags(ctx Context, opts AllFlagsOptions) map[string]FlagState {
+	compiled := c.config.Load()
+	if compiled == nil {
+		return map[string]FlagState{}
+	}
+
+	evalCtx := c.evalContext(ctx)
+	states := make(map[string]FlagState, len((*compiled).Flags))
+	for key, flag := range (*compiled).Flags {
+		if !opts.includes(flag) {
+			continue
+		}
+		value, res := eval.EvalValue(flag, key, evalCtx)
+		states[key] = FlagState{
+			Value:   value,
+			Variant: res.Variant,
+			Reason:  Reason(res.Reason),
+		}
+	}
+
+	return states
+}
+
+func (o AllFlagsOptions) includes(flag *config.CompiledFlag) bool {
+	if o.ClientSideOnly && !flag.ClientSide {
+		return false
+	}
+	if len(o.Tags) == 0 {
+		return true
+	}
+	for _, tag := range o.Tags {
+		if slices.Contains(flag.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,11 @@
 package goff
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"sync/atomic"
+	"time"
 
 	"github.com/0mjs/goff/internal/config"
 	"github.com/0mjs/goff/internal/eval"
@@ -11,63 +15,152 @@ import (
 type Client interface {
 	Boolean(key string, ctx Context, def bool) bool
 	String(key string, ctx Context, def string) string
+	Int(key string, ctx Context, def int64) int64
+	Float(key string, ctx Context, def float64) float64
+	BooleanDetail(key string, ctx Context, def bool) EvaluationDetail[bool]
+	StringDetail(key string, ctx Context, def string) EvaluationDetail[string]
+	IntDetail(key string, ctx Context, def int64) EvaluationDetail[int64]
+	FloatDetail(key string, ctx Context, def float64) EvaluationDetail[float64]
+	JSON(key string, ctx Context, dst any) error
+	AllFlags(ctx Context, opts AllFlagsOptions) map[string]FlagState
 	Close() error
 }
 
 type client struct {
 	config *atomic.Pointer[*config.Compiled]
 	hooks  *Hooks
+	sticky StickyStore
+	clock  func() time.Time
 	closer func() error
 }
 
 // Boolean evaluates a boolean flag.
 func (c *client) Boolean(key string, ctx Context, def bool) bool {
-	compiled := c.config.Load()
-	if compiled == nil {
-		return def
-	}
+	return c.BooleanDetail(key, ctx, def).Value
+}
 
-	flag := (*compiled).Flags[key]
+// BooleanDetail evaluates a boolean flag and reports how it was resolved.
+func (c *client) BooleanDetail(key string, ctx Context, def bool) EvaluationDetail[bool] {
+	flag, version, loaded := c.lookup(key)
+	return evaluate(c, flag, version, loaded, key, ctx, def, eval.EvalBoolDetail)
+}
 
-	evalCtx := eval.Context{
-		Key:   ctx.Key,
-		Attrs: ctx.Attrs,
-	}
+// String evaluates a string flag.
+func (c *client) String(key string, ctx Context, def string) string {
+	return c.StringDetail(key, ctx, def).Value
+}
 
-	result, reason := eval.EvalBool(flag, key, evalCtx, def)
+// StringDetail evaluates a string flag and reports how it was resolved.
+func (c *client) StringDetail(key string, ctx Context, def string) EvaluationDetail[string] {
+	flag, version, loaded := c.lookup(key)
+	return evaluate(c, flag, version, loaded, key, ctx, def, eval.EvalStringDetail)
+}
 
-	if c.hooks != nil && c.hooks.AfterEval != nil {
-		variant := "false"
-		if result {
-			variant = "true"
-		}
-		c.hooks.AfterEval(key, variant, Reason(reason))
+// Int evaluates an integer flag.
+func (c *client) Int(key string, ctx Context, def int64) int64 {
+	return c.IntDetail(key, ctx, def).Value
+}
+
+// IntDetail evaluates an integer flag and reports how it was resolved.
+func (c *client) IntDetail(key string, ctx Context, def int64) EvaluationDetail[int64] {
+	flag, version, loaded := c.lookup(key)
+	return evaluate(c, flag, version, loaded, key, ctx, def, eval.EvalIntDetail)
+}
+
+// Float evaluates a floating-point flag.
+func (c *client) Float(key string, ctx Context, def float64) float64 {
+	return c.FloatDetail(key, ctx, def).Value
+}
+
+// FloatDetail evaluates a floating-point flag and reports how it was resolved.
+func (c *client) FloatDetail(key string, ctx Context, def float64) EvaluationDetail[float64] {
+	flag, version, loaded := c.lookup(key)
+	return evaluate(c, flag, version, loaded, key, ctx, def, eval.EvalFloatDetail)
+}
+
+// JSON evaluates a json flag and decodes the selected value into dst, which
+// must be a pointer. If the flag does not produce a value, dst is left
+// unchanged and ErrFlagNotFound, ErrTypeMismatch or ErrNoValue is returned.
+func (c *client) JSON(key string, ctx Context, dst any) error {
+	flag, _, loaded := c.lookup(key)
+
+	res, ok := eval.EvalJSON(flag, key, c.evalContext(ctx))
+
+	if loaded && c.hooks != nil && c.hooks.AfterEval != nil {
+		c.hooks.AfterEval(key, res.Variant, Reason(res.Reason))
 	}
 
-	return result
+	if !ok {
+		if err := reasonError(Reason(res.Reason)); err != nil {
+			return err
+		}
+		return ErrNoValue
+	}
+	if err := json.Unmarshal(flag.Payloads[res.Variant], dst); err != nil {
+		return fmt.Errorf("goff: decode flag %q: %w", key, err)
+	}
+	return nil
 }
 
-// String evaluates a string flag.
-func (c *client) String(key string, ctx Context, def string) string {
-	compiled := c.config.Load()
-	if compiled == nil {
-		return def
+// evalFunc is the signature shared by the typed eval.Eval*Detail functions.
+type evalFunc[T FlagValue] func(*config.CompiledFlag, string, eval.Context, T) (T, eval.Result)
+
+// evaluate runs fn against flag, reports the result to the hooks and wraps it
+// in an EvaluationDetail. Hooks are not called before the first snapshot is
+// loaded, when every flag falls back to its default.
+func evaluate[T FlagValue](c *client, flag *config.CompiledFlag, version uint64, loaded bool, key string, ctx Context, def T, fn evalFunc[T]) EvaluationDetail[T] {
+	result, res := fn(flag, key, c.evalContext(ctx), def)
+
+	if loaded && c.hooks != nil && c.hooks.AfterEval != nil {
+		c.hooks.AfterEval(key, formatValue(result), Reason(res.Reason))
 	}
 
-	flag := (*compiled).Flags[key]
+	return newDetail(result, res, version)
+}
 
-	evalCtx := eval.Context{
-		Key:   ctx.Key,
-		Attrs: ctx.Attrs,
+// formatValue renders a flag value the way hooks report it.
+func formatValue(v any) string {
+	switch t := v.(type) {
+	case bool:
+		return strconv.FormatBool(t)
+	case string:
+		return t
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
 	}
+	return ""
+}
 
-	result, reason := eval.EvalString(flag, key, evalCtx, def)
+// lookup returns the flag from the current snapshot along with the
+// snapshot's version. The flag is nil if it does not exist; loaded is false
+// if no snapshot has been loaded.
+func (c *client) lookup(key string) (flag *config.CompiledFlag, version uint64, loaded bool) {
+	compiled := c.config.Load()
+	if compiled == nil {
+		return nil, 0, false
+	}
+	return (*compiled).Flags[key], (*compiled).Version, true
+}
 
-	if c.hooks != nil && c.hooks.AfterEval != nil {
-		c.hooks.AfterEval(key, result, Reason(reason))
+func (c *client) evalContext(ctx Context) eval.Context {
+	return eval.Context{
+		Key:    ctx.Key,
+		Attrs:  ctx.Attrs,
+		Sticky: c.sticky,
+		Clock:  c.clock,
 	}
+}
 
-	return result
+// Object evaluates a json flag and decodes the selected value into a new T.
+// It returns def if the flag cannot be evaluated or decoded.
+func Object[T any](c Client, key string, ctx Context, def T) T {
+	var v T
+	if err := c.JSON(key, ctx, &v); err != nil {
+		return def
+	}
+	return v
 }
 
 // Close closes the client and stops any background operations.
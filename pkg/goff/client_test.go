@@ -1,8 +1,15 @@
 package goff
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/0mjs/goff/internal/config"
 )
 
 func TestClient_Bool(t *testing.T) {
@@ -99,3 +106,349 @@ func TestClient_WithHooks(t *testing.T) {
 		t.Errorf("hook variant = %v, want 'true' or 'false'", calledVariant)
 	}
 }
+
+func TestClient_HooksWithoutSnapshot(t *testing.T) {
+	calls := 0
+	c := &client{
+		config: &atomic.Pointer[*config.Compiled]{},
+		hooks: &Hooks{
+			AfterEval: func(flag, variant string, reason Reason) { calls++ },
+		},
+	}
+
+	ctx := Context{Key: "user:1"}
+	if got := c.Boolean("new_checkout", ctx, true); !got {
+		t.Errorf("Boolean() = %v, want default true", got)
+	}
+	if got := c.StringDetail("theme", ctx, "light"); got.Value != "light" || got.Reason != Missing {
+		t.Errorf("StringDetail() = %+v, want default with Missing", got)
+	}
+	if got := BoolFlag(c, "new_checkout").Get(ctx, true); !got {
+		t.Errorf("Get() = %v, want default true", got)
+	}
+	var dst map[string]any
+	if err := c.JSON("banner", ctx, &dst); !errors.Is(err, ErrFlagNotFound) {
+		t.Errorf("JSON() error = %v, want ErrFlagNotFound", err)
+	}
+	// Without a snapshot nothing is evaluated, so nothing is reported
+	if calls != 0 {
+		t.Errorf("AfterEval called %d times without a snapshot, want 0", calls)
+	}
+}
+
+func TestClient_Int(t *testing.T) {
+	client, err := New(
+		WithFile("../../testdata/flags.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"plan": "pro"}}
+	if got := client.Int("batch_size", ctx, 0); got != 1000 {
+		t.Errorf("Int() = %v, want 1000", got)
+	}
+
+	ctx.Attrs["plan"] = "basic"
+	if got := client.Int("batch_size", ctx, 0); got != 100 && got != 500 {
+		t.Errorf("Int() = %v, want 100 or 500", got)
+	}
+
+	if got := client.Int("missing", ctx, 7); got != 7 {
+		t.Errorf("Int() = %v, want 7 (caller default)", got)
+	}
+}
+
+func TestClient_Float(t *testing.T) {
+	client, err := New(
+		WithFile("../../testdata/flags.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"plan": "pro"}}
+	if got := client.Float("sample_rate", ctx, 0); got != 0.5 {
+		t.Errorf("Float() = %v, want 0.5", got)
+	}
+
+	ctx.Attrs["plan"] = "basic"
+	if got := client.Float("sample_rate", ctx, 0); got != 0.01 {
+		t.Errorf("Float() = %v, want 0.01 (flag default)", got)
+	}
+}
+
+func TestClient_JSON(t *testing.T) {
+	client, err := New(
+		WithFile("../../testdata/flags.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	type retryPolicy struct {
+		MaxAttempts int    `json:"max_attempts"`
+		Backoff     string `json:"backoff"`
+	}
+
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"plan": "pro"}}
+
+	var policy retryPolicy
+	if err := client.JSON("retry_policy", ctx, &policy); err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	if policy.MaxAttempts != 10 || policy.Backoff != "100ms" {
+		t.Errorf("JSON() = %+v, want aggressive policy", policy)
+	}
+
+	ctx.Attrs["plan"] = "basic"
+	policy = Object(client, "retry_policy", ctx, retryPolicy{})
+	if policy.MaxAttempts != 3 {
+		t.Errorf("Object() = %+v, want conservative policy", policy)
+	}
+
+	def := retryPolicy{MaxAttempts: 1}
+	if got := Object(client, "missing", ctx, def); got != def {
+		t.Errorf("Object() = %+v, want default", got)
+	}
+
+	if err := client.JSON("checkout_theme", ctx, &policy); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("JSON() error = %v, want ErrTypeMismatch", err)
+	}
+	if err := client.JSON("missing", ctx, &policy); !errors.Is(err, ErrFlagNotFound) {
+		t.Errorf("JSON() error = %v, want ErrFlagNotFound", err)
+	}
+}
+
+func TestClient_BooleanDetail(t *testing.T) {
+	client, err := New(
+		WithFile("../../testdata/flags.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"plan": "basic"}}
+
+	detail := client.BooleanDetail("new_checkout", ctx, false)
+	if detail.Reason != Percent || detail.RuleIndex != -1 || detail.Err != nil {
+		t.Errorf("BooleanDetail() = %+v, want fallthrough split", detail)
+	}
+	if detail.Variant != "true" && detail.Variant != "false" {
+		t.Errorf("BooleanDetail() variant = %q, want 'true' or 'false'", detail.Variant)
+	}
+	if detail.ConfigVersion != 1 {
+		t.Errorf("BooleanDetail() config version = %d, want 1", detail.ConfigVersion)
+	}
+
+	detail = client.BooleanDetail("disabled_flag", ctx, false)
+	if !detail.Value || detail.Reason != Disabled || detail.Variant != "" {
+		t.Errorf("BooleanDetail() = %+v, want flag default with Disabled", detail)
+	}
+
+	detail = client.BooleanDetail("checkout_theme", ctx, true)
+	if !detail.Value || detail.Reason != TypeMismatch || !errors.Is(detail.Err, ErrTypeMismatch) {
+		t.Errorf("BooleanDetail() = %+v, want caller default with TypeMismatch", detail)
+	}
+
+	detail = client.BooleanDetail("missing", ctx, true)
+	if detail.Reason != Missing || !errors.Is(detail.Err, ErrFlagNotFound) {
+		t.Errorf("BooleanDetail() = %+v, want Missing", detail)
+	}
+}
+
+func TestClient_StringDetail(t *testing.T) {
+	client, err := New(
+		WithFile("../../testdata/flags.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"theme": "dark"}}
+
+	detail := client.StringDetail("checkout_theme", ctx, "default")
+	if detail.Value != "black" || detail.Variant != "black" || detail.Reason != Match || detail.RuleIndex != 0 {
+		t.Errorf("StringDetail() = %+v, want rule 0 match", detail)
+	}
+}
+
+func TestReason_String(t *testing.T) {
+	tests := []struct {
+		reason Reason
+		want   string
+	}{
+		{Match, "rule_match"},
+		{Percent, "split"},
+		{Default, "default"},
+		{Disabled, "disabled"},
+		{Missing, "missing"},
+		{Error, "error"},
+		{TargetMatch, "target_match"},
+		{TypeMismatch, "type_mismatch"},
+		{MissingBucketKey, "missing_bucket_key"},
+		{Inactive, "inactive"},
+		{PrerequisiteFailed, "prerequisite_failed"},
+		{BucketKeyFallthrough, "bucket_key_fallthrough"},
+		{Reason(200), "Reason(200)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.reason.String(); got != tt.want {
+			t.Errorf("Reason(%d).String() = %q, want %q", tt.reason, got, tt.want)
+		}
+	}
+
+	data, err := json.Marshal(map[string]Reason{"reason": Percent})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != `{"reason":"split"}` {
+		t.Errorf("json.Marshal() = %s, want reason by name", data)
+	}
+}
+
+func TestClient_AllFlags(t *testing.T) {
+	client, err := New(
+		WithFile("../../testdata/flags.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"plan": "pro", "theme": "dark"}}
+
+	all := client.AllFlags(ctx, AllFlagsOptions{})
+	if len(all) != 6 {
+		t.Errorf("AllFlags() returned %d flags, want 6", len(all))
+	}
+	if got := all["checkout_theme"]; got.Value != "black" || got.Reason != Match {
+		t.Errorf("AllFlags()[checkout_theme] = %+v, want black via rule match", got)
+	}
+	if got := all["batch_size"]; got.Value != int64(1000) {
+		t.Errorf("AllFlags()[batch_size] = %+v, want 1000", got)
+	}
+	if got := all["disabled_flag"]; got.Value != true || got.Reason != Disabled {
+		t.Errorf("AllFlags()[disabled_flag] = %+v, want flag default", got)
+	}
+	policy, ok := all["retry_policy"].Value.(map[string]any)
+	if !ok || policy["max_attempts"] != 10 {
+		t.Errorf("AllFlags()[retry_policy] = %+v, want aggressive payload", all["retry_policy"])
+	}
+
+	// The returned payload belongs to the caller
+	if ok {
+		policy["max_attempts"] = 0
+	}
+	again, _ := client.AllFlags(ctx, AllFlagsOptions{})["retry_policy"].Value.(map[string]any)
+	if again["max_attempts"] != 10 {
+		t.Errorf("AllFlags()[retry_policy] after mutating a previous result = %+v, want aggressive payload", again)
+	}
+	var decoded struct {
+		MaxAttempts int `json:"max_attempts"`
+	}
+	if err := client.JSON("retry_policy", ctx, &decoded); err != nil || decoded.MaxAttempts != 10 {
+		t.Errorf("JSON() after mutating an AllFlags result = %+v, %v, want max_attempts 10", decoded, err)
+	}
+
+	clientSide := client.AllFlags(ctx, AllFlagsOptions{ClientSideOnly: true})
+	if len(clientSide) != 2 {
+		t.Errorf("AllFlags(ClientSideOnly) returned %d flags, want 2", len(clientSide))
+	}
+	if _, ok := clientSide["batch_size"]; ok {
+		t.Error("AllFlags(ClientSideOnly) leaked a server-side flag")
+	}
+
+	tagged := client.AllFlags(ctx, AllFlagsOptions{Tags: []string{"ui", "backend"}})
+	if len(tagged) != 2 {
+		t.Errorf("AllFlags(Tags) returned %d flags, want 2", len(tagged))
+	}
+	if _, ok := tagged["checkout_theme"]; !ok {
+		t.Error("AllFlags(Tags) missing checkout_theme")
+	}
+	if _, ok := tagged["batch_size"]; !ok {
+		t.Error("AllFlags(Tags) missing batch_size")
+	}
+}
+
+func TestClient_WithClock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	yaml := `
+version: 1
+flags:
+  ramp:
+    enabled: true
+    type: "bool"
+    variants:
+      true: 0
+      false: 100
+    rollout:
+      variant: "true"
+      steps:
+        - at: 2024-06-01T00:00:00Z
+          percent: 100
+    default: false
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+	client, err := New(WithFile(path), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := Context{Key: "user:1"}
+	if client.Boolean("ramp", ctx, true) {
+		t.Error("Boolean() before the rollout = true, want false")
+	}
+
+	now = now.Add(48 * time.Hour)
+	if !client.Boolean("ramp", ctx, false) {
+		t.Error("Boolean() after the rollout = false, want true")
+	}
+}
+
+func TestClient_PrerequisiteDetail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	yaml := `
+version: 1
+flags:
+  new_checkout:
+    enabled: false
+    type: "bool"
+    default: false
+  new_checkout_upsell:
+    enabled: true
+    type: "bool"
+    variants:
+      true: 100
+      false: 0
+    prerequisites:
+      - flag: new_checkout
+        variant: "true"
+    default: false
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := New(WithFile(path))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	detail := client.BooleanDetail("new_checkout_upsell", Context{Key: "user:1"}, true)
+	if detail.Value || detail.Reason != PrerequisiteFailed || detail.Prerequisite != "new_checkout" || detail.Err != nil {
+		t.Errorf("BooleanDetail() = %+v, want flag default blocked by new_checkout", detail)
+	}
+}
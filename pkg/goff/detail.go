@@ -0,0 +1,43 @@
+package goff
+
+import (
+	"github.com/0mjs/goff/internal/eval"
+)
+
+// EvaluationDetail describes the outcome of a single flag evaluation.
+type EvaluationDetail[T any] struct {
+	Value         T
+	Variant       string // selected variant; empty when a default value was used
+	Reason        Reason
+	RuleIndex     int    // index of the matching rule, or -1
+	ConfigVersion uint64 // version of the configuration snapshot that was used
+	Prerequisite  string // with PrerequisiteFailed, the key of the flag that blocked evaluation
+	SkippedRule   int    // with BucketKeyFallthrough, the index of the rule skipped for lack of its bucket_by attribute
+	Err           error  // set when Reason is Missing, TypeMismatch or Error
+}
+
+func newDetail[T any](value T, res eval.Result, version uint64) EvaluationDetail[T] {
+	return EvaluationDetail[T]{
+		Value:         value,
+		Variant:       res.Variant,
+		Reason:        Reason(res.Reason),
+		RuleIndex:     res.RuleIndex,
+		ConfigVersion: version,
+		Prerequisite:  res.Prerequisite,
+		SkippedRule:   res.SkippedRule,
+		Err:           reasonError(Reason(res.Reason)),
+	}
+}
+
+// reasonError maps failure reasons to their sentinel errors.
+func reasonError(reason Reason) error {
+	switch reason {
+	case Missing:
+		return ErrFlagNotFound
+	case TypeMismatch:
+		return ErrTypeMismatch
+	case Error:
+		return ErrEvaluation
+	}
+	return nil
+}
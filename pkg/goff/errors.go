@@ -0,0 +1,17 @@
+package goff
+
+import "errors"
+
+var (
+	// ErrFlagNotFound is returned when the requested flag is not defined.
+	ErrFlagNotFound = errors.New("goff: flag not found")
+	// ErrTypeMismatch is returned when a flag is evaluated as a different type
+	// than it is declared with.
+	ErrTypeMismatch = errors.New("goff: flag type mismatch")
+	// ErrNoValue is returned when evaluation resolves neither a variant nor a
+	// default value.
+	ErrNoValue = errors.New("goff: flag has no value")
+	// ErrEvaluation is returned when a flag's configuration could not be
+	// evaluated, for example because its percentages do not cover every user.
+	ErrEvaluation = errors.New("goff: evaluation failed")
+)
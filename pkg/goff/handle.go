@@ -0,0 +1,118 @@
+package goff
+
+import (
+	"sync/atomic"
+
+	"github.com/0mjs/goff/internal/config"
+	"github.com/0mjs/goff/internal/eval"
+)
+
+// FlagValue is the set of value types supported by typed flag handles.
+type FlagValue interface {
+	bool | string | int64 | float64
+}
+
+// Flag is a typed handle to a single flag. It resolves the flag once per
+// configuration snapshot instead of looking it up by key on every call, and
+// re-binds automatically when the configuration is reloaded.
+//
+// Handles are safe for concurrent use and are meant to be created once and
+// kept, e.g. in a package-level variable.
+type Flag[T FlagValue] struct {
+	key     string
+	client  *client
+	binding atomic.Pointer[flagBinding]
+	eval    evalFunc[T]
+
+	// detail is used for Client implementations other than the one returned
+	// by New, such as test doubles.
+	detail func(key string, ctx Context, def T) EvaluationDetail[T]
+}
+
+// flagBinding caches the result of a key lookup in one snapshot.
+type flagBinding struct {
+	snapshot *config.Compiled
+	flag     *config.CompiledFlag
+}
+
+// NewFlag returns a typed handle to the flag key.
+func NewFlag[T FlagValue](c Client, key string) *Flag[T] {
+	var fn, detail any
+	switch any(*new(T)).(type) {
+	case bool:
+		fn, detail = evalFunc[bool](eval.EvalBoolDetail), c.BooleanDetail
+	case string:
+		fn, detail = evalFunc[string](eval.EvalStringDetail), c.StringDetail
+	case int64:
+		fn, detail = evalFunc[int64](eval.EvalIntDetail), c.IntDetail
+	case float64:
+		fn, detail = evalFunc[float64](eval.EvalFloatDetail), c.FloatDetail
+	}
+
+	f := &Flag[T]{key: key, eval: fn.(evalFunc[T])}
+	if cl, ok := c.(*client); ok {
+		f.client = cl
+	} else {
+		f.detail = detail.(func(string, Context, T) EvaluationDetail[T])
+	}
+	return f
+}
+
+// BoolFlag returns a handle to a boolean flag.
+func BoolFlag(c Client, key string) *Flag[bool] {
+	return NewFlag[bool](c, key)
+}
+
+// StringFlag returns a handle to a string flag.
+func StringFlag(c Client, key string) *Flag[string] {
+	return NewFlag[string](c, key)
+}
+
+// IntFlag returns a handle to an integer flag.
+func IntFlag(c Client, key string) *Flag[int64] {
+	return NewFlag[int64](c, key)
+}
+
+// FloatFlag returns a handle to a floating-point flag.
+func FloatFlag(c Client, key string) *Flag[float64] {
+	return NewFlag[float64](c, key)
+}
+
+// Key returns the flag key the handle refers to.
+func (f *Flag[T]) Key() string {
+	return f.key
+}
+
+// Get evaluates the flag.
+func (f *Flag[T]) Get(ctx Context, def T) T {
+	return f.Detail(ctx, def).Value
+}
+
+// Detail evaluates the flag and reports how it was resolved.
+func (f *Flag[T]) Detail(ctx Context, def T) EvaluationDetail[T] {
+	if f.detail != nil {
+		return f.detail(f.key, ctx, def)
+	}
+
+	flag, version, loaded := f.bind()
+	return evaluate(f.client, flag, version, loaded, f.key, ctx, def, f.eval)
+}
+
+// bind returns the flag from the client's current snapshot, looking it up
+// only when the snapshot has changed since the last call. loaded is false if
+// no snapshot has been loaded.
+func (f *Flag[T]) bind() (flag *config.CompiledFlag, version uint64, loaded bool) {
+	current := f.client.config.Load()
+	if current == nil {
+		return nil, 0, false
+	}
+	snapshot := *current
+
+	if b := f.binding.Load(); b != nil && b.snapshot == snapshot {
+		return b.flag, snapshot.Version, true
+	}
+
+	b := &flagBinding{snapshot: snapshot, flag: snapshot.Flags[f.key]}
+	f.binding.Store(b)
+	return b.flag, snapshot.Version, true
+}
@@ -0,0 +1,77 @@
+package goff
+
+import (
+	"testing"
+)
+
+// These benchmarks mirror internal/eval/flag_bench_test.go but go through the
+// public API, so the difference is the cost of the key lookup and context
+// conversion that handles avoid.
+
+func newBenchClient(b *testing.B) Client {
+	b.Helper()
+	client, err := New(
+		WithFile("../../testdata/flags.yaml"),
+	)
+	if err != nil {
+		b.Fatalf("New() error = %v", err)
+	}
+	b.Cleanup(func() { client.Close() })
+	return client
+}
+
+func BenchmarkClient_Boolean(b *testing.B) {
+	client := newBenchClient(b)
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"plan": "basic"}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = client.Boolean("new_checkout", ctx, false)
+	}
+}
+
+func BenchmarkBoolFlag_Get(b *testing.B) {
+	handle := BoolFlag(newBenchClient(b), "new_checkout")
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"plan": "basic"}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = handle.Get(ctx, false)
+	}
+}
+
+func BenchmarkClient_String(b *testing.B) {
+	client := newBenchClient(b)
+	ctx := Context{Key: "user:123", Attrs: map[string]any{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = client.String("checkout_theme", ctx, "default")
+	}
+}
+
+func BenchmarkStringFlag_Get(b *testing.B) {
+	handle := StringFlag(newBenchClient(b), "checkout_theme")
+	ctx := Context{Key: "user:123", Attrs: map[string]any{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = handle.Get(ctx, "default")
+	}
+}
+
+func BenchmarkBoolFlag_Get_Concurrent(b *testing.B) {
+	handle := BoolFlag(newBenchClient(b), "new_checkout")
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := Context{Key: "user:123", Attrs: map[string]any{}}
+		for pb.Next() {
+			_ = handle.Get(ctx, false)
+		}
+	})
+}
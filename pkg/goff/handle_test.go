@@ -0,0 +1,94 @@
+package goff
+
+import (
+	"testing"
+
+	"github.com/0mjs/goff/internal/config"
+)
+
+func TestFlag_Get(t *testing.T) {
+	client, err := New(
+		WithFile("../../testdata/flags.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := Context{Key: "user:123", Attrs: map[string]any{"plan": "pro", "theme": "dark"}}
+
+	checkout := BoolFlag(client, "new_checkout")
+	if got, want := checkout.Get(ctx, false), client.Boolean("new_checkout", ctx, false); got != want {
+		t.Errorf("BoolFlag.Get() = %v, want %v", got, want)
+	}
+
+	theme := StringFlag(client, "checkout_theme")
+	if got := theme.Get(ctx, "default"); got != "black" {
+		t.Errorf("StringFlag.Get() = %v, want black", got)
+	}
+
+	batch := IntFlag(client, "batch_size")
+	if got := batch.Get(ctx, 0); got != 1000 {
+		t.Errorf("IntFlag.Get() = %v, want 1000", got)
+	}
+
+	rate := FloatFlag(client, "sample_rate")
+	if got := rate.Detail(ctx, 0); got.Value != 0.5 || got.Reason != Match {
+		t.Errorf("FloatFlag.Detail() = %+v, want 0.5 via rule match", got)
+	}
+
+	missing := BoolFlag(client, "missing")
+	if got := missing.Detail(ctx, true); !got.Value || got.Reason != Missing {
+		t.Errorf("BoolFlag.Detail() = %+v, want caller default with Missing", got)
+	}
+}
+
+func TestFlag_RebindsOnReload(t *testing.T) {
+	c, err := New(
+		WithFile("../../testdata/flags.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer c.Close()
+
+	ctx := Context{Key: "user:1"}
+	handle := BoolFlag(c, "disabled_flag")
+	if got := handle.Get(ctx, false); !got {
+		t.Fatalf("Get() = %v, want true (flag default)", got)
+	}
+
+	// Simulate the watcher swapping in a new snapshot
+	next, err := config.Compile(&config.Config{
+		Version: 1,
+		Flags: map[string]config.Flag{
+			"disabled_flag": {Type: "bool", Default: false},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	next.Version = 2
+	c.(*client).config.Store(&next)
+
+	detail := handle.Detail(ctx, true)
+	if detail.Value || detail.ConfigVersion != 2 {
+		t.Errorf("Detail() = %+v, want false from snapshot 2", detail)
+	}
+}
+
+// stubClient is a Client that is not backed by a configuration snapshot.
+type stubClient struct {
+	Client
+}
+
+func (stubClient) BooleanDetail(key string, ctx Context, def bool) EvaluationDetail[bool] {
+	return EvaluationDetail[bool]{Value: true, Reason: Match}
+}
+
+func TestFlag_OtherClient(t *testing.T) {
+	handle := BoolFlag(stubClient{}, "anything")
+	if got := handle.Get(Context{Key: "user:1"}, false); !got {
+		t.Errorf("Get() = %v, want true from stub client", got)
+	}
+}
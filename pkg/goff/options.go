@@ -10,13 +10,16 @@ import (
 )
 
 type optionConfig struct {
-	filePath      string
-	autoReload    time.Duration
-	hooks         *Hooks
-	compiled      *atomic.Pointer[*config.Compiled]
-	watcher       *fsnotify.Watcher
-	stopWatcher   chan struct{}
-	watcherDone   chan struct{}
+	filePath    string
+	autoReload  time.Duration
+	hooks       *Hooks
+	sticky      StickyStore
+	clock       func() time.Time
+	compiled    *atomic.Pointer[*config.Compiled]
+	version     uint64 // version of the most recently loaded snapshot
+	watcher     *fsnotify.Watcher
+	stopWatcher chan struct{}
+	watcherDone chan struct{}
 }
 
 // Option configures a Client.
@@ -46,6 +49,24 @@ func WithHooks(hooks Hooks) Option {
 	}
 }
 
+// WithStickyStore records the variants assigned by splits of flags marked
+// sticky: true, so that contexts keep their variant when the split changes.
+func WithStickyStore(store StickyStore) Option {
+	return func(cfg *optionConfig) error {
+		cfg.sticky = store
+		return nil
+	}
+}
+
+// WithClock sets the clock used for time-based targeting such as scheduled
+// rollouts and relative before/after conditions. It defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(cfg *optionConfig) error {
+		cfg.clock = now
+		return nil
+	}
+}
+
 // New creates a new Client with the given options.
 func New(opts ...Option) (Client, error) {
 	cfg := &optionConfig{
@@ -69,6 +90,8 @@ func New(opts ...Option) (Client, error) {
 		return nil, fmt.Errorf("load config: %w", err)
 	}
 
+	cfg.version++
+	initialConfig.Version = cfg.version
 	cfg.compiled.Store(&initialConfig)
 
 	// Set up auto-reload if requested
@@ -101,6 +124,8 @@ func New(opts ...Option) (Client, error) {
 	return &client{
 		config: cfg.compiled,
 		hooks:  cfg.hooks,
+		sticky: cfg.sticky,
+		clock:  cfg.clock,
 		closer: closer,
 	}, nil
 }
@@ -152,13 +177,13 @@ func watchFile(cfg *optionConfig) {
 
 func reloadConfig(cfg *optionConfig, lastError *time.Time, errorCount *int, maxErrors int, maxBackoff time.Duration) {
 	now := time.Now()
-	
+
 	// Exponential backoff: only try if enough time has passed
 	backoff := time.Duration(*errorCount) * time.Second
 	if backoff > maxBackoff {
 		backoff = maxBackoff
 	}
-	
+
 	if now.Sub(*lastError) < backoff {
 		return
 	}
@@ -179,7 +204,8 @@ func reloadConfig(cfg *optionConfig, lastError *time.Time, errorCount *int, maxE
 	}
 
 	// Success - update config atomically
+	cfg.version++
+	newConfig.Version = cfg.version
 	cfg.compiled.Store(&newConfig)
 	*errorCount = 0
 }
-
@@ -1,14 +1,51 @@
 package goff
 
+import "strconv"
+
 // Reason is a small enum for audit/metrics.
 type Reason uint8
 
 const (
-	Match Reason = iota
-	Percent
-	Default
-	Disabled
-	Missing
-	Error
+	Match                Reason = iota // a targeting rule matched
+	Percent                            // no rule matched; the fallthrough percentage split decided
+	Default                            // no variants apply; the flag default was used
+	Disabled                           // the flag is disabled
+	Missing                            // the flag does not exist
+	Error                              // evaluation failed; the caller default was used
+	TargetMatch                        // the context key was individually targeted
+	TypeMismatch                       // the flag was evaluated as a different type
+	MissingBucketKey                   // the attribute a split buckets by is absent; the flag default was used
+	Inactive                           // the flag is outside its activation schedule; the flag default was used
+	PrerequisiteFailed                 // a prerequisite flag did not produce its required variant; the flag default was used
+	BucketKeyFallthrough               // a matching rule's bucket_by attribute was absent; the flag's split or default decided
 )
 
+var reasonNames = [...]string{
+	Match:              "rule_match",
+	Percent:            "split",
+	Default:            "default",
+	Disabled:           "disabled",
+	Missing:            "missing",
+	Error:              "error",
+	TargetMatch:        "target_match",
+	TypeMismatch:       "type_mismatch",
+	MissingBucketKey:   "missing_bucket_key",
+	Inactive:           "inactive",
+	PrerequisiteFailed: "prerequisite_failed",
+
+	BucketKeyFallthrough: "bucket_key_fallthrough",
+}
+
+// String returns the snake_case name of the reason.
+func (r Reason) String() string {
+	if int(r) < len(reasonNames) {
+		return reasonNames[r]
+	}
+	return "Reason(" + strconv.Itoa(int(r)) + ")"
+}
+
+// MarshalText implements encoding.TextMarshaler so reasons appear by name in
+// JSON and structured logs.
+func (r Reason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
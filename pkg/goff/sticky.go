@@ -0,0 +1,107 @@
+package goff
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/0mjs/goff/internal/eval"
+)
+
+// StickyStore persists the variant each context was assigned by a split of a
+// flag marked sticky: true. See WithStickyStore.
+//
+// Assignments are recorded with the flag's hashed salt, and one made under a
+// different salt is ignored, so changing a flag's salt reassigns everyone.
+// A stored variant is also ignored once the split no longer gives it any
+// traffic. Store errors are treated as misses and never fail an evaluation.
+type StickyStore = eval.StickyStore
+
+// MemoryStickyStore is an in-process StickyStore that keeps at most a fixed
+// number of assignments, evicting the least recently used.
+type MemoryStickyStore struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[stickyKey]*list.Element
+	order    *list.List // most recently used at the front
+}
+
+type stickyKey struct {
+	flag, key string
+}
+
+type stickyEntry struct {
+	key     stickyKey
+	salt    uint64
+	variant string
+}
+
+// NewMemoryStickyStore returns a store holding up to capacity assignments.
+// A capacity of 0 or less means unlimited.
+func NewMemoryStickyStore(capacity int) *MemoryStickyStore {
+	return &MemoryStickyStore{
+		capacity: capacity,
+		entries:  make(map[stickyKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the variant assigned to key for flagKey under salt.
+func (s *MemoryStickyStore) Get(flagKey, key string, salt uint64) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[stickyKey{flagKey, key}]
+	if !ok {
+		return "", false, nil
+	}
+	entry := elem.Value.(*stickyEntry)
+	if entry.salt != salt {
+		return "", false, nil
+	}
+	s.order.MoveToFront(elem)
+	return entry.variant, true, nil
+}
+
+// Set records the variant assigned to key for flagKey under salt.
+func (s *MemoryStickyStore) Set(flagKey, key string, salt uint64, variant string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := stickyKey{flagKey, key}
+	if elem, ok := s.entries[k]; ok {
+		entry := elem.Value.(*stickyEntry)
+		entry.salt = salt
+		entry.variant = variant
+		s.order.MoveToFront(elem)
+		return nil
+	}
+
+	s.entries[k] = s.order.PushFront(&stickyEntry{key: k, salt: salt, variant: variant})
+	if s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*stickyEntry).key)
+	}
+	return nil
+}
+
+// Reset removes every assignment for flagKey.
+func (s *MemoryStickyStore) Reset(flagKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, elem := range s.entries {
+		if k.flag == flagKey {
+			s.order.Remove(elem)
+			delete(s.entries, k)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of stored assignments.
+func (s *MemoryStickyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
@@ -0,0 +1,69 @@
+package goff
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SQLiteStickyStore is a StickyStore backed by a SQLite database, for
+// assignments that must survive restarts or be shared by processes on one
+// host. The caller opens the database with the driver of their choice, e.g.
+// github.com/mattn/go-sqlite3, and remains responsible for closing it.
+type SQLiteStickyStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStickyStore returns a store using db, creating its table if needed.
+func NewSQLiteStickyStore(db *sql.DB) (*SQLiteStickyStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS goff_sticky_assignments (
+			flag_key    TEXT    NOT NULL,
+			context_key TEXT    NOT NULL,
+			salt        INTEGER NOT NULL,
+			variant     TEXT    NOT NULL,
+			PRIMARY KEY (flag_key, context_key)
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("create sticky table: %w", err)
+	}
+	return &SQLiteStickyStore{db: db}, nil
+}
+
+// Get returns the variant assigned to key for flagKey under salt.
+func (s *SQLiteStickyStore) Get(flagKey, key string, salt uint64) (string, bool, error) {
+	var variant string
+	err := s.db.QueryRow(
+		"SELECT variant FROM goff_sticky_assignments WHERE flag_key = ? AND context_key = ? AND salt = ?",
+		flagKey, key, int64(salt), // SQLite integers are signed
+	).Scan(&variant)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("get sticky assignment: %w", err)
+	}
+	return variant, true, nil
+}
+
+// Set records the variant assigned to key for flagKey under salt.
+func (s *SQLiteStickyStore) Set(flagKey, key string, salt uint64, variant string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO goff_sticky_assignments (flag_key, context_key, salt, variant)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (flag_key, context_key) DO UPDATE SET salt = excluded.salt, variant = excluded.variant
+	`, flagKey, key, int64(salt), variant)
+	if err != nil {
+		return fmt.Errorf("set sticky assignment: %w", err)
+	}
+	return nil
+}
+
+// Reset removes every assignment for flagKey.
+func (s *SQLiteStickyStore) Reset(flagKey string) error {
+	if _, err := s.db.Exec("DELETE FROM goff_sticky_assignments WHERE flag_key = ?", flagKey); err != nil {
+		return fmt.Errorf("reset sticky assignments: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package goff
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func testStickyStore(t *testing.T, store StickyStore) {
+	t.Helper()
+
+	if _, ok, err := store.Get("flag", "user:1", 1); ok || err != nil {
+		t.Fatalf("Get() on empty store = %v, %v", ok, err)
+	}
+
+	if err := store.Set("flag", "user:1", 1, "a"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if v, ok, err := store.Get("flag", "user:1", 1); v != "a" || !ok || err != nil {
+		t.Errorf("Get() = %q, %v, %v, want a", v, ok, err)
+	}
+
+	// Assignments made under another salt are ignored
+	if _, ok, _ := store.Get("flag", "user:1", 2); ok {
+		t.Error("Get() with a different salt should miss")
+	}
+
+	if err := store.Set("flag", "user:1", 2, "b"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if v, ok, _ := store.Get("flag", "user:1", 2); v != "b" || !ok {
+		t.Errorf("Get() after overwrite = %q, %v, want b", v, ok)
+	}
+
+	// Large salts survive storage unchanged
+	if err := store.Set("flag", "user:2", ^uint64(0), "c"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if v, ok, _ := store.Get("flag", "user:2", ^uint64(0)); v != "c" || !ok {
+		t.Errorf("Get() with max salt = %q, %v, want c", v, ok)
+	}
+
+	if err := store.Set("other", "user:1", 1, "x"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Reset("flag"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if _, ok, _ := store.Get("flag", "user:1", 2); ok {
+		t.Error("Reset() should remove the flag's assignments")
+	}
+	if _, ok, _ := store.Get("other", "user:1", 1); !ok {
+		t.Error("Reset() should keep other flags' assignments")
+	}
+}
+
+func TestMemoryStickyStore(t *testing.T) {
+	testStickyStore(t, NewMemoryStickyStore(0))
+}
+
+func TestMemoryStickyStore_Evicts(t *testing.T) {
+	store := NewMemoryStickyStore(2)
+	store.Set("flag", "user:1", 0, "a")
+	store.Set("flag", "user:2", 0, "a")
+	store.Get("flag", "user:1", 0) // user:2 is now least recently used
+	store.Set("flag", "user:3", 0, "a")
+
+	if store.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", store.Len())
+	}
+	if _, ok, _ := store.Get("flag", "user:2", 0); ok {
+		t.Error("least recently used assignment should be evicted")
+	}
+	if _, ok, _ := store.Get("flag", "user:1", 0); !ok {
+		t.Error("recently used assignment should be kept")
+	}
+}
+
+func TestSQLiteStickyStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // every connection gets its own :memory: database
+
+	store, err := NewSQLiteStickyStore(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteStickyStore() error = %v", err)
+	}
+	testStickyStore(t, store)
+}
+
+func TestClient_Sticky(t *testing.T) {
+	store := NewMemoryStickyStore(0)
+	client, err := New(
+		WithFile("../../testdata/flags.yaml"),
+		WithStickyStore(store),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	want := client.String("checkout_theme", Context{Key: "user:1"}, "")
+	if store.Len() != 1 {
+		t.Fatalf("store has %d assignments, want 1", store.Len())
+	}
+	if v, ok, _ := store.Get("checkout_theme", "user:1", 0); !ok || v != want {
+		t.Errorf("stored assignment = %q, %v, want %q", v, ok, want)
+	}
+
+	// Flags that are not sticky are not recorded
+	client.Boolean("new_checkout", Context{Key: "user:1"}, false)
+	if store.Len() != 1 {
+		t.Errorf("store has %d assignments, want 1", store.Len())
+	}
+}